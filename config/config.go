@@ -4,13 +4,17 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
@@ -19,16 +23,97 @@ import (
 type Option func(v *viper.Viper) error
 
 // WithFile will override implicit configuration file lookups and specify an
-// absolute path to a config file to load
+// absolute path to a config file to load. Files without a TOML, YAML or JSON
+// extension are read as TOML.
 func WithFile(p string) Option {
 	return func(v *viper.Viper) error {
 		if p != "" {
 			v.SetConfigFile(p)
+			if configTypes[filepath.Ext(p)] == "" {
+				v.SetConfigType("toml")
+			}
 		}
 		return nil
 	}
 }
 
+// configTypes maps supported config file extensions to their format
+var configTypes = map[string]string{
+	".toml": "toml",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+}
+
+// lookupExts are the config file extensions found by an implicit config file
+// lookup, in order of preference
+var lookupExts = []string{".toml", ".yaml", ".yml", ".json"}
+
+// configFs limits viper's implicit config file lookup to the formats in
+// configTypes, preferring them in lookupExts order. viper searches
+// viper.SupportedExts, JSON first, which holds formats not read by this
+// package e.g. HCL.
+type configFs struct {
+	afero.Fs
+}
+
+// Stat reports config files of other formats, or with a preferred sibling
+// e.g. `name.json` next to `name.toml`, as not existing
+func (fs configFs) Stat(name string) (os.FileInfo, error) {
+	ext := filepath.Ext(name)
+	notExist := &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	if configTypes[ext] == "" {
+		return nil, notExist
+	}
+	for _, e := range lookupExts {
+		if e == ext {
+			break
+		}
+		if _, err := fs.Fs.Stat(strings.TrimSuffix(name, ext) + e); err == nil {
+			return nil, notExist
+		}
+	}
+	return fs.Fs.Stat(name)
+}
+
+// readFile reads a config file into a new viper instance, the format is
+// detected by the file extension defaulting to TOML
+func readFile(p string) (*viper.Viper, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	typ, ok := configTypes[filepath.Ext(p)]
+	if !ok {
+		typ = "toml"
+	}
+	fv := viper.New()
+	fv.SetConfigType(typ)
+	err = fv.ReadConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", p, err)
+	}
+	return fv, nil
+}
+
+// mergeMaps deep merges src into dst, values in src replace those in dst.
+// Unlike viper.MergeConfigMap values of a different type are replaced, as
+// formats decode numbers to different types. Nested maps of src are copied.
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			dm, ok := dst[k].(map[string]interface{})
+			if !ok {
+				dm = map[string]interface{}{}
+				dst[k] = dm
+			}
+			mergeMaps(dm, sm)
+			continue
+		}
+		dst[k] = sv
+	}
+}
+
 // BindFlag returns an Option function allowing the binding of CLI flags to
 // configuration values
 func BindFlag(key string, flag *pflag.Flag) Option {
@@ -36,19 +121,23 @@ func BindFlag(key string, flag *pflag.Flag) Option {
 		if flag == nil {
 			return nil
 		}
+		if s := readState(v); s != nil {
+			s.flags[strings.ToLower(key)] = flag
+		}
 		return v.BindPFlag(key, flag)
 	}
 }
 
 // ViperWithDefaults constructs a new viper instance pre-configured with
 // SOON_ defaults.
-// - TOML format
+// - TOML, YAML or JSON format, detected by file extension, a TOML file is
+// preferred when several exist
 // - Loads files from `/etc/name/name.toml`, `$HOME/.config/name.toml`
 // - Env vars as `NAME_FIELD`
 // - Names that are hyphenated will have the hyphens removed e.g. "new-project" would be accessed "NEWPROJECT"
 func ViperWithDefaults(name string) *viper.Viper {
 	v := viper.New()
-	v.SetConfigType("toml")
+	v.SetFs(configFs{afero.NewOsFs()})
 	v.SetConfigName(name)
 	// Set default config paths
 	v.AddConfigPath(fmt.Sprintf("/etc/%s", name))
@@ -57,36 +146,126 @@ func ViperWithDefaults(name string) *viper.Viper {
 	return v
 }
 
-// ReadInConfig constructs a new Config instance
+// ReadInConfig constructs a new Config instance. Once config files, sources,
+// env vars and flags are merged, references in string values read from
+// config files and sources are expanded: `${ENV_VAR}` to an env var,
+// `${key.path}` to the value of another key and `${ref:-default}` to a
+// default when the reference is unset or empty, `$${` is a literal `${`.
+// Values of env vars and flags are not expanded. Unresolved references and cycles are returned as
+// *ReferenceError. Encrypted `ENC[...]` values are decrypted before
+// references are expanded, see Encrypt. References to fields tagged
+// `secret:"true"` expand to the resolved secret, see ResolveSecrets, values
+// holding decrypted or resolved secrets are redacted by Report, Snapshot
+// and Diff.
+// Secret references in fields tagged `secret:"true"` are resolved, see
+// ResolveSecrets, then the loaded configuration is validated with the
+// `validate` struct tags of c, see Validate and WithValidator.
 func ReadInConfig(v *viper.Viper, c interface{}, opts ...Option) error {
-	for _, opt := range opts {
-		err := opt(v)
-		if err != nil {
-			return err
-		}
+	s, err := applyOptions(v, opts)
+	if err != nil {
+		return err
 	}
-	err := bindEnvs(v, c)
+	err = s.readInConfig(v, c)
 	if err != nil {
 		return err
 	}
+	return s.finalize(v, c)
+}
+
+// finalize checks for unknown keys, resolves secrets, validates c and logs
+// changes once it has been read
+func (s *state) finalize(v *viper.Viper, c interface{}) error {
+	if s.report != nil {
+		s.report.v, s.report.state = v, s
+	}
+	err := s.checkStrict(v, c)
+	if err != nil {
+		return err
+	}
+	err = ResolveSecrets(c)
+	if err != nil {
+		return err
+	}
+	if s.validator {
+		err = Validate(v, c)
+	} else {
+		err = validateTags(v, c)
+	}
+	if err != nil {
+		return err
+	}
+	recordSecretKeys(c, s.secretKeys)
+	if s.changeLog != nil {
+		s.changeLog(c)
+	}
+	return nil
+}
+
+// readInConfig binds env vars and reads the config file into c
+func (s *state) readInConfig(v *viper.Viper, c interface{}) error {
+	settings, err := s.readConfigFile(v, c)
+	if err != nil {
+		return err
+	}
+	return s.mergeLayers(v, c, settings)
+}
+
+// readConfigFile binds env vars, loads sources and dotenv files and reads
+// the config file, returning its settings
+func (s *state) readConfigFile(v *viper.Viper, c interface{}) (map[string]interface{}, error) {
+	s.recordPresets(c)
+	err := bindEnvs(v, c)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
 	switch err := v.ReadInConfig(); err.(type) {
-	case nil, viper.ConfigFileNotFoundError:
+	case nil:
+		fv, err := readFile(v.ConfigFileUsed())
+		if err != nil {
+			return nil, err
+		}
+		s.recordFile(v.ConfigFileUsed(), fv.AllKeys())
+		settings = fv.AllSettings()
+	case viper.ConfigFileNotFoundError:
 		break
 	default:
+		return nil, err
+	}
+	err = s.loadSources()
+	if err != nil {
+		return nil, err
+	}
+	err = s.loadDotEnv()
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// mergeLayers merges the settings of sources and then dotenv files over the
+// config file settings and unmarshals the result into c
+func (s *state) mergeLayers(v *viper.Viper, c interface{}, settings map[string]interface{}) error {
+	for _, layer := range []map[string]interface{}{s.sourceSettings(), s.dotEnvSettings(v, c)} {
+		conformMaps(settings, layer)
+		mergeMaps(settings, layer)
+	}
+	err := v.MergeConfigMap(settings)
+	if err != nil {
 		return err
 	}
-	return v.Unmarshal(c)
+	return s.unmarshal(v, c)
 }
 
 // ViperWithDir constructs a new viper instance pre-configured with
 // SOON_ defaults which is used to load all config from a directory
-// - TOML format
+// - TOML, YAML or JSON format, detected by file extension
 // - Loads files from `/etc/name/`
 // - Env vars as `NAME_FIELD`
 // - Names that are hyphenated will have the hyphens removed e.g. "new-project" would be accessed "NEWPROJECT"
 func ViperWithDir(name string) (*viper.Viper, string) {
 	v := viper.New()
-	v.SetConfigType("toml")
+	v.SetFs(configFs{afero.NewOsFs()})
 	// Set default config paths
 	path := fmt.Sprintf("/etc/%s", name)
 	v.AddConfigPath(path)
@@ -94,37 +273,51 @@ func ViperWithDir(name string) (*viper.Viper, string) {
 	return v, path
 }
 
-// ReadInAllDirConfig reads and merges all config files inside a directory
+// ReadInAllDirConfig reads and merges all config files inside a directory.
+// TOML, YAML and JSON files may be mixed, the format of each file is
+// detected by its extension. Files are deep merged in layers, later layers
+// override earlier ones:
+//  1. `base` e.g. `base.toml`
+//  2. all other files in file name order, excluding profile overlays
+//  3. overlays for the profiles active in the `NAME_PROFILE` env var, a comma
+//     separated list applied in order e.g. `staging.toml`
+//  4. `local` e.g. `local.toml`
+//
+// Overlays for profiles declared with WithProfiles are only applied when
+// active, Report.Layers lists the files which were applied. Secrets are
+// resolved and the merged configuration is validated as with ReadInConfig.
 func ReadInAllDirConfig(v *viper.Viper, p string, c interface{}, opts ...Option) error {
-	err := ReadInConfig(v, c, opts...)
+	s, err := applyOptions(v, opts)
+	if err != nil {
+		return err
+	}
+	err = s.readInAllDirConfig(v, p, c)
+	if err != nil {
+		return err
+	}
+	return s.finalize(v, c)
+}
+
+func (s *state) readInAllDirConfig(v *viper.Viper, p string, c interface{}) error {
+	settings, err := s.readConfigFile(v, c)
 	if err != nil {
 		return err
 	}
 	if v.ConfigFileUsed() == "" {
-		dir, err := os.ReadDir(p)
+		files, err := s.dirLayers(v, p)
 		if err != nil {
 			return err
 		}
-		for i, d := range dir {
-			fileName := strings.Split(d.Name(), ".")[0]
-			if filepath.Ext(d.Name()) == ".toml" {
-				v.SetConfigName(fileName)
-				if i == 0 {
-					err = ReadInConfig(v, c, opts...)
-					if err != nil {
-						return err
-					}
-				} else {
-					err = v.MergeInConfig()
-					if err != nil {
-						return err
-					}
-				}
+		for _, fp := range files {
+			fv, err := readFile(fp)
+			if err != nil {
+				return err
 			}
+			mergeMaps(settings, fv.AllSettings())
+			s.recordFile(fp, fv.AllKeys())
 		}
-		return v.Unmarshal(c)
 	}
-	return nil
+	return s.mergeLayers(v, c, settings)
 }
 
 // lowerFirst lowercases the first character of a string
@@ -135,38 +328,105 @@ func lowerFirst(s string) string {
 }
 
 // bindEnvs uses reflection to bind environment variables with viper.Unmarshal
-// which cannot use viper.AutomaticEnv. It takes an interface which should be
-// a struct or pointer to a struct and an optional slice of strings.
+// which cannot use viper.AutomaticEnv, registering any `default` struct tag
+// values along the way. Pointers to structs and structs tagged
+// `mapstructure:",squash"` are walked, map entries are bound by key e.g.
+// NAME_LABELS_TEAM and slices of structs by index e.g. NAME_BROKERS_0_HOST.
+// It takes an interface which should be a struct or pointer to a struct and
+// an optional slice of strings.
 func bindEnvs(v *viper.Viper, iface interface{}, parts ...string) error {
-	var ifv reflect.Value
-	var ift reflect.Type
-	if reflect.TypeOf(iface).Kind() == reflect.Ptr {
-		ifv = reflect.Indirect(reflect.ValueOf(iface))
-		ift = reflect.Indirect(reflect.ValueOf(iface)).Type()
-	} else {
-		ifv = reflect.ValueOf(iface)
-		ift = reflect.TypeOf(iface)
-	}
+	return walkFields(iface, func(f field) error {
+		err := setDefault(v, f)
+		if err != nil {
+			return err
+		}
+		switch {
+		case f.value.Kind() == reflect.Map:
+			return bindMapEnvs(v, f)
+		case isStructSlice(f.value.Type()):
+			// bound by index when unmarshalled, see bindIndexedEnvs
+			return nil
+		}
+		return v.BindEnv(f.key)
+	}, parts...)
+}
+
+// A field is a leaf struct field and the configuration key it is bound to
+type field struct {
+	key   string // dotted configuration key e.g. `log.level`
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// walkFields uses reflection to call fn for every leaf field of a struct.
+// It takes an interface which should be a struct or pointer to a struct and
+// an optional slice of strings which prefix the field keys. Field values are
+// settable when iface is a pointer.
+func walkFields(iface interface{}, fn func(f field) error, parts ...string) error {
+	return walkValue(reflect.Indirect(reflect.ValueOf(iface)), fn, parts...)
+}
+
+// walkSetFields calls fn for every leaf field of a struct as walkFields,
+// skipping the fields of nil pointers to structs e.g. optional sections
+// which were not configured
+func walkSetFields(iface interface{}, fn func(f field) error) error {
+	return walk(reflect.Indirect(reflect.ValueOf(iface)), true, fn)
+}
+
+func walkValue(ifv reflect.Value, fn func(f field) error, parts ...string) error {
+	return walk(ifv, false, fn, parts...)
+}
+
+func walk(ifv reflect.Value, skipNil bool, fn func(f field) error, parts ...string) error {
+	ift := ifv.Type()
 	for i := 0; i < ift.NumField(); i++ {
+		sf := ift.Field(i)
+		if sf.PkgPath != "" {
+			// unexported fields cannot be set by viper.Unmarshal
+			continue
+		}
 		val := ifv.Field(i)
-		tv := ift.Field(i).Tag.Get("mapstructure")
+		tv, opts, _ := strings.Cut(sf.Tag.Get("mapstructure"), ",")
+		if tv == "-" {
+			continue
+		}
 		if tv == "" {
 			// default to field name, with lowercased first char `Log` => log
-			tv = lowerFirst(ift.Field(i).Name)
+			tv = lowerFirst(sf.Name)
 		}
-		switch val.Kind() {
-		case reflect.Struct:
+		if val.Kind() == reflect.Ptr && val.Type().Elem().Kind() == reflect.Struct && !isLeaf(val.Type()) {
+			// nil pointers are walked as a zero struct so their fields are
+			// still bound
+			if val.IsNil() {
+				if skipNil {
+					continue
+				}
+				val = reflect.New(val.Type().Elem())
+			}
+			val = val.Elem()
+		}
+		switch {
+		case val.Kind() == reflect.Struct && !isLeaf(val.Type()):
 			// If the field is a struct the name of the field is appended
-			// to the parts slice and BindEnvs is called again with the nested
-			// struct and the parts slice
-			err := bindEnvs(v, val.Interface(), append(parts, tv)...)
+			// to the parts slice and walkValue is called again with the
+			// nested struct and the parts slice, squashed structs share
+			// the parts of their parent
+			p := append(parts, tv)
+			if strings.Contains(opts, "squash") {
+				p = parts
+			}
+			err := walk(val, skipNil, fn, p...)
 			if err != nil {
 				return err
 			}
 		default:
 			// If it is not a struct the field name is joined with the parts
-			// slice with `.` and viper.BindEnv is called with that name
-			err := v.BindEnv(strings.Join(append(parts, tv), "."))
+			// slice with `.` to give the field key
+			err := fn(field{
+				key:   strings.Join(append(parts, tv), "."),
+				value: val,
+				tag:   sf.Tag,
+			})
 			if err != nil {
 				return err
 			}
@@ -176,7 +436,93 @@ func bindEnvs(v *viper.Viper, iface interface{}, parts ...string) error {
 }
 
 func setEnv(v *viper.Viper, name string) {
-	envPrefix := strings.ReplaceAll(name, "-", "")
-	v.SetEnvPrefix(envPrefix)
+	v.SetEnvPrefix(strings.ReplaceAll(name, "-", ""))
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 }
+
+// envPrefix returns the env prefix set on v, which viper does not expose
+func envPrefix(v *viper.Viper) string {
+	f := reflect.ValueOf(v).Elem().FieldByName("envPrefix")
+	if f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// envName returns the name of the env var bound to a configuration key
+func envName(v *viper.Viper, key string) string {
+	prefix := envPrefix(v)
+	if prefix == "" {
+		return strings.ToUpper(key)
+	}
+	return strings.ToUpper(strings.ReplaceAll(prefix+"_"+key, ".", "_"))
+}
+
+// state holds the settings of a single read which viper itself does not
+// hold, it is set up by the Options passed to the read
+type state struct {
+	files         []configFile           // config files read in merge order
+	flags         map[string]*pflag.Flag // flags bound by lowercased key
+	profiles      []string               // declared profile overlay names
+	strict        bool                   // reject unknown config file keys
+	strictEnv     bool                   // report unknown prefixed env vars
+	envWarn       func(error)            // called with unknown env vars if set
+	sources       []RemoteSource         // sources merged over config files
+	sourced       []sourced              // settings loaded from sources
+	sourceTimeout time.Duration          // bounds loading sources if set
+	dotEnvFiles   []string               // dotenv files in load order
+	dotEnv        map[string]dotEnvVar   // vars loaded from dotenv files
+	preset        map[string]bool        // keys holding a value before the read
+	key           func() (string, error) // loads the decryption key
+	secretKeys    map[string]bool        // keys holding decrypted values or references to them
+	validator     bool                   // call the Validator of c
+	changeLog     func(c interface{})    // logs changes once loaded
+	report        *Report                // describes the read once loaded
+}
+
+// reads holds the state of the reads applying their options by viper
+// instance, so Options which configure the read rather than the viper
+// instance can find it. Entries only exist while options are applied.
+var reads = struct {
+	sync.Mutex
+	m map[*viper.Viper]*state
+}{m: map[*viper.Viper]*state{}}
+
+// readState returns the state of the read applying options to v, or nil if
+// an Option is applied to v outside of a read
+func readState(v *viper.Viper) *state {
+	reads.Lock()
+	defer reads.Unlock()
+	return reads.m[v]
+}
+
+// withState returns an Option configuring the state of a read, it does
+// nothing when applied to a viper instance directly
+func withState(fn func(s *state)) Option {
+	return func(v *viper.Viper) error {
+		if s := readState(v); s != nil {
+			fn(s)
+		}
+		return nil
+	}
+}
+
+// applyOptions applies opts to v returning the state of a new read
+func applyOptions(v *viper.Viper, opts []Option) (*state, error) {
+	s := &state{flags: map[string]*pflag.Flag{}}
+	reads.Lock()
+	reads.m[v] = s
+	reads.Unlock()
+	defer func() {
+		reads.Lock()
+		delete(reads.m, v)
+		reads.Unlock()
+	}()
+	for _, opt := range opts {
+		err := opt(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
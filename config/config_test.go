@@ -3,6 +3,7 @@ package config_test
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/spf13/pflag"
@@ -40,8 +41,8 @@ func TestConfig(t *testing.T) {
 	for name, tc := range tcs {
 		t.Run(name, func(t *testing.T) {
 			// example env var overrides
-			os.Setenv(envVarKey(tc.envPrefix, "LOG_LEVEL"), "error")
-			os.Setenv(envVarKey(tc.envPrefix, "LOG_ENVTEST"), "env")
+			t.Setenv(envVarKey(tc.envPrefix, "LOG_LEVEL"), "error")
+			t.Setenv(envVarKey(tc.envPrefix, "LOG_ENVTEST"), "env")
 
 			// example flag override
 			fs := pflag.NewFlagSet(tc.name, pflag.ContinueOnError)
@@ -90,6 +91,47 @@ func TestConfig(t *testing.T) {
 
 }
 
+func TestBindFlag(t *testing.T) {
+	type Config struct {
+		Name string
+	}
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("name", "", "")
+	if err := fs.Set("name", "flag"); err != nil {
+		t.Fatal(err)
+	}
+
+	// applied to viper directly
+	v := viper.New()
+	err := config.BindFlag("name", fs.Lookup("name"))(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", v.GetString("name"))
+
+	// wrapped by a caller option returning on the first error
+	options := func(opts ...config.Option) config.Option {
+		return func(v *viper.Viper) error {
+			for _, opt := range opts {
+				if err := opt(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	var c Config
+	var r config.Report
+	v = config.ViperWithDefaults("bindflag")
+	err = config.ReadInConfig(v, &c, options(
+		config.BindFlag("name", fs.Lookup("name")),
+		config.WithReport(&r),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", c.Name)
+	assert.Equal(t, config.Settings{
+		{Key: "name", Value: "flag", Source: config.SourceFlag, Origin: "--name"},
+	}, r.Explain(&c))
+}
+
 func envVarKey(prefix, key string) string {
 	return fmt.Sprintf("%s_%s", prefix, key)
 }
@@ -166,3 +208,82 @@ func TestReadInAllConfig(t *testing.T) {
 	}
 
 }
+
+func TestReadInConfig_Formats(t *testing.T) {
+	type Log struct {
+		Console bool
+		Level   string
+		Name    string
+		Custom  string `mapstructure:"customTag"`
+	}
+	type Server struct {
+		Port  int
+		Hosts []string
+	}
+	type Config struct {
+		Log    Log
+		Server Server
+	}
+
+	t.Run("yaml file", func(t *testing.T) {
+		c := Config{}
+		v := config.ViperWithDefaults("formats")
+		err := config.ReadInConfig(v, &c, config.WithFile("testdata/formats/test.yml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "warn", c.Log.Level)
+	})
+	t.Run("implicit lookup", func(t *testing.T) {
+		dir := t.TempDir()
+		v := config.ViperWithDefaults("lookup")
+		v.AddConfigPath(dir)
+		writeFile(t, filepath.Join(dir, "lookup.hcl"), "log {\n  level = \"hcl\"\n}\n")
+		c := Config{}
+		err := config.ReadInConfig(v, &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "", v.ConfigFileUsed(), "formats other than TOML, YAML and JSON are not found")
+
+		writeFile(t, filepath.Join(dir, "lookup.json"), `{"log": {"level": "json"}}`)
+		writeFile(t, filepath.Join(dir, "lookup.yaml"), "log:\n  level: yaml\n")
+		writeFile(t, filepath.Join(dir, "lookup.toml"), "[log]\nlevel = \"toml\"\n")
+		err = config.ReadInConfig(v, &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "toml", c.Log.Level, "TOML is preferred")
+
+		v = config.ViperWithDefaults("lookup")
+		v.AddConfigPath(dir)
+		os.Remove(filepath.Join(dir, "lookup.toml"))
+		err = config.ReadInConfig(v, &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "yaml", c.Log.Level, "YAML is preferred to JSON")
+	})
+	t.Run("mixed directory", func(t *testing.T) {
+		c := Config{}
+		v := viper.New()
+		tp := "testdata/mixed"
+		v.AddConfigPath(tp)
+		err := config.ReadInAllDirConfig(v, tp, &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, Config{
+			Log: Log{
+				Console: true,
+				Level:   "debug",
+				Name:    "json",
+				Custom:  "yaml",
+			},
+			Server: Server{
+				Port:  6000,
+				Hosts: []string{"a", "b"},
+			},
+		}, c)
+	})
+}
@@ -0,0 +1,279 @@
+// Package configtest provides helpers for testing code which loads its
+// configuration with the config package. Config files are written to a temp
+// dir and env vars are scoped to the test, so tests do not depend on or leak
+// into the environment.
+package configtest
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/spf13/pflag"
+	"go.soon.build/kit/config"
+)
+
+// An Option configures the config files, env vars and flags Load reads
+type Option func(s *setup)
+
+type setup struct {
+	files map[string]string
+	env   map[string]string
+	args  []string
+	opts  []config.Option
+}
+
+// WithTOML returns an Option writing contents to `base.toml` in the config
+// dir
+func WithTOML(contents string) Option {
+	return WithFile("base.toml", contents)
+}
+
+// WithFile returns an Option writing contents to a file in the config dir,
+// the file name selects the format and layer e.g. `staging.yaml`, see
+// config.ReadInAllDirConfig
+func WithFile(name, contents string) Option {
+	return func(s *setup) {
+		s.files[name] = contents
+	}
+}
+
+// WithEnv returns an Option setting the env var bound to a configuration
+// key for the test e.g. `log.level` sets NAME_LOG_LEVEL. Keys may also be
+// given as env var names without the prefix e.g. `PROFILE`.
+func WithEnv(key, value string) Option {
+	return func(s *setup) {
+		s.env[key] = value
+	}
+}
+
+// WithArgs returns an Option parsing command line args with the flags
+// registered for the configuration struct by config.RegisterFlags e.g.
+// `--log.level=debug`
+func WithArgs(args ...string) Option {
+	return func(s *setup) {
+		s.args = append(s.args, args...)
+	}
+}
+
+// WithOptions returns an Option passing opts to config.ReadInAllDirConfig
+func WithOptions(opts ...config.Option) Option {
+	return func(s *setup) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// Load loads a configuration struct of type T for the app name, failing the
+// test on error. Config files are read from a temp dir, env vars prefixed
+// for name which are not set by WithEnv are unset for the test. As env vars
+// are set with t.Setenv, Load cannot be used in parallel tests.
+//
+// Example:
+//
+//	c := configtest.Load[Config](t, "app",
+//		configtest.WithTOML("[log]\nlevel = \"info\"\n"),
+//		configtest.WithEnv("log.format", "json"),
+//		configtest.WithArgs("--log.level=debug"),
+//	)
+func Load[T any](t testing.TB, name string, opts ...Option) T {
+	t.Helper()
+	c, err := Read[T](t, name, opts...)
+	if err != nil {
+		t.Fatalf("error loading config: %v", err)
+	}
+	return c
+}
+
+// Read loads a configuration struct of type T as Load does, returning the
+// error rather than failing the test e.g. to test validation
+func Read[T any](t testing.TB, name string, opts ...Option) (T, error) {
+	t.Helper()
+	s := &setup{
+		files: map[string]string{},
+		env:   map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	var c T
+	dir := t.TempDir()
+	for fn, contents := range s.files {
+		err := os.WriteFile(filepath.Join(dir, fn), []byte(contents), 0o600)
+		if err != nil {
+			t.Fatalf("error writing config file: %v", err)
+		}
+	}
+	prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "")) + "_"
+	for _, kv := range os.Environ() {
+		env, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(env, prefix) {
+			// restored once the test completes
+			t.Setenv(env, "")
+			os.Unsetenv(env)
+		}
+	}
+	for key, val := range s.env {
+		t.Setenv(prefix+strings.ToUpper(strings.ReplaceAll(key, ".", "_")), val)
+	}
+	v, _ := config.ViperWithDir(name)
+	copts := s.opts
+	if s.args != nil {
+		fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+		copts = append(config.RegisterFlags(fs, &c), copts...)
+		err := fs.Parse(s.args)
+		if err != nil {
+			return c, err
+		}
+	}
+	err := config.ReadInAllDirConfig(v, dir, &c, copts...)
+	return c, err
+}
+
+// Diff compares two configuration structs key by key, returning a line for
+// each key whose value differs e.g. `log.level: want "debug", got "info"`.
+// Nested structs are compared by their fields, other values as a whole.
+func Diff(want, got interface{}) []string {
+	wkeys, wvals := flatten(want)
+	gkeys, gvals := flatten(got)
+	var diffs []string
+	for _, k := range wkeys {
+		gv, ok := gvals[k]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got no key", k, format(wvals[k])))
+			continue
+		}
+		if !reflect.DeepEqual(wvals[k], gv) {
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got %s", k, format(wvals[k]), format(gv)))
+		}
+	}
+	for _, k := range gkeys {
+		if _, ok := wvals[k]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: want no key, got %s", k, format(gvals[k])))
+		}
+	}
+	return diffs
+}
+
+// AssertEqual asserts two configuration structs are equal, reporting the
+// keys which differ
+func AssertEqual(t testing.TB, want, got interface{}) bool {
+	t.Helper()
+	diffs := Diff(want, got)
+	if len(diffs) > 0 {
+		t.Errorf("unexpected config:\n\t%s", strings.Join(diffs, "\n\t"))
+		return false
+	}
+	return true
+}
+
+// AssertKeys asserts the values of the given dotted configuration keys of
+// a configuration struct, other keys are not compared. Values are equal if
+// they are deeply equal or format the same e.g. a time.Duration and `5s`.
+//
+// Example:
+//
+//	configtest.AssertKeys(t, c, map[string]interface{}{
+//		"log.level":    "debug",
+//		"http.timeout": "5s",
+//	})
+func AssertKeys(t testing.TB, got interface{}, want map[string]interface{}) bool {
+	t.Helper()
+	_, gvals := flatten(got)
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var diffs []string
+	for _, k := range keys {
+		gv, ok := gvals[k]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got no key", k, format(want[k])))
+		case !reflect.DeepEqual(want[k], gv) && fmt.Sprint(want[k]) != fmt.Sprint(gv):
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got %s", k, format(want[k]), format(gv)))
+		}
+	}
+	if len(diffs) > 0 {
+		t.Errorf("unexpected config:\n\t%s", strings.Join(diffs, "\n\t"))
+		return false
+	}
+	return true
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// flatten returns the dotted keys of the leaf fields of a struct, keyed as
+// by the config package, and their values
+func flatten(c interface{}) ([]string, map[string]interface{}) {
+	var keys []string
+	vals := map[string]interface{}{}
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			name, opts, _ := strings.Cut(sf.Tag.Get("mapstructure"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				a := []rune(sf.Name)
+				a[0] = unicode.ToLower(a[0])
+				name = string(a)
+			}
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr && isStruct(fv.Type().Elem()) {
+				if fv.IsNil() {
+					fv = reflect.New(fv.Type().Elem())
+				}
+				fv = fv.Elem()
+			}
+			if isStruct(fv.Type()) {
+				p := prefix + name + "."
+				if strings.Contains(opts, "squash") {
+					p = prefix
+				}
+				walk(fv, p)
+				continue
+			}
+			keys = append(keys, prefix+name)
+			vals[prefix+name] = fv.Interface()
+		}
+	}
+	v := reflect.Indirect(reflect.ValueOf(c))
+	if v.Kind() == reflect.Struct {
+		walk(v, "")
+	}
+	return keys, vals
+}
+
+// isStruct returns true for struct types walked by their fields, structs
+// with a text form such as time.Time or url.URL are compared as a whole
+func isStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(textMarshalerType) && !pt.Implements(stringerType)
+}
+
+// format formats a value for a diff, quoting strings
+func format(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", v)
+}
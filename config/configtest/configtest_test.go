@@ -0,0 +1,86 @@
+package configtest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config/configtest"
+)
+
+type testConfig struct {
+	Name string `default:"kit"`
+	Log  struct {
+		Level  string `validate:"oneof=debug info"`
+		Format string
+	}
+	HTTP struct {
+		Timeout time.Duration `mapstructure:"timeout"`
+	} `mapstructure:"http"`
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("CONFIGTEST_NAME", "leaked")
+	c := configtest.Load[testConfig](t, "configtest",
+		configtest.WithTOML("[log]\nlevel = \"info\"\nformat = \"text\"\n"),
+		configtest.WithFile("local.toml", "[http]\ntimeout = \"5s\"\n"),
+		configtest.WithEnv("log.format", "json"),
+		configtest.WithArgs("--log.level=debug"),
+	)
+	configtest.AssertKeys(t, c, map[string]interface{}{
+		"name":         "kit",
+		"log.level":    "debug",
+		"log.format":   "json",
+		"http.timeout": time.Second * 5,
+	})
+}
+
+func TestRead(t *testing.T) {
+	_, err := configtest.Read[testConfig](t, "configtest",
+		configtest.WithEnv("LOG_LEVEL", "trace"),
+	)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "log.level (CONFIGTEST_LOG_LEVEL)")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	want := testConfig{Name: "kit"}
+	want.Log.Level = "debug"
+	want.HTTP.Timeout = time.Second
+	got := want
+	got.Log.Level = "info"
+	got.HTTP.Timeout = time.Minute
+	assert.Equal(t, []string{
+		`log.level: want "debug", got "info"`,
+		`http.timeout: want 1s, got 1m0s`,
+	}, configtest.Diff(want, got))
+	assert.Empty(t, configtest.Diff(want, &want))
+}
+
+// recorder records errors reported by assertions
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestAssertKeys(t *testing.T) {
+	c := testConfig{}
+	c.HTTP.Timeout = time.Second * 5
+	r := &recorder{TB: t}
+	assert.True(t, configtest.AssertKeys(r, c, map[string]interface{}{"http.timeout": "5s"}))
+	assert.False(t, configtest.AssertKeys(r, c, map[string]interface{}{
+		"http.timeout": "1s",
+		"http.port":    80,
+	}))
+	assert.Equal(t, []string{
+		"unexpected config:\n\thttp.port: want 80, got no key\n\thttp.timeout: want \"1s\", got 5s",
+	}, r.errs)
+}
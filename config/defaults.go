@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// setDefault registers the value of a field's `default` struct tag as the
+// viper default for its key. Fields already holding a non-zero value are
+// left alone so values pre-populated in the struct are kept.
+//
+// Example:
+//
+//	type Config struct {
+//		Level   string        `default:"info"`
+//		Timeout time.Duration `default:"5s"`
+//		Brokers []string      `default:"a:9092,b:9092"`
+//	}
+func setDefault(v *viper.Viper, f field) error {
+	d, ok := f.tag.Lookup("default")
+	if !ok || !f.value.IsZero() {
+		return nil
+	}
+	val, err := parseValue(f.value.Type(), d)
+	if err != nil {
+		return fmt.Errorf("invalid default for %s: %v", f.key, err)
+	}
+	v.SetDefault(f.key, val.Interface())
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parseValue parses a string into a value of type t, types with a decode hook
+// are parsed by it and other slices are parsed from comma separated values
+func parseValue(t reflect.Type, s string) (reflect.Value, error) {
+	val := reflect.New(t).Elem()
+	if d, ok, err := decodeString(t, s); ok {
+		if err != nil {
+			return val, err
+		}
+		return reflect.ValueOf(d), nil
+	}
+	switch {
+	case t.Kind() == reflect.Slice:
+		if s == "" {
+			return reflect.MakeSlice(t, 0, 0), nil
+		}
+		items := strings.Split(s, ",")
+		val = reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			e, err := parseValue(t.Elem(), strings.TrimSpace(item))
+			if err != nil {
+				return val, err
+			}
+			val.Index(i).Set(e)
+		}
+		return val, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		val.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return val, err
+		}
+		val.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return val, err
+		}
+		val.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return val, err
+		}
+		val.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return val, err
+		}
+		val.SetFloat(n)
+	default:
+		return val, fmt.Errorf("unsupported type %s", t)
+	}
+	return val, nil
+}
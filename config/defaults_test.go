@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestReadInConfig_Defaults(t *testing.T) {
+	type Log struct {
+		Console bool   `default:"false"`
+		Level   string `default:"debug"`
+		Name    string `default:"default"`
+		Custom  string `mapstructure:"customTag" default:"default"`
+	}
+	type Server struct {
+		Port    int           `default:"5000"`
+		Ratio   float64       `default:"0.5"`
+		Timeout time.Duration `default:"5s"`
+		Hosts   []string      `default:"a, b"`
+		Retries uint          `default:"3"`
+	}
+	type Config struct {
+		Log    Log
+		Server Server
+	}
+
+	c := Config{
+		Server: Server{Port: 8000},
+	}
+	v := config.ViperWithDefaults("defaults")
+	err := config.ReadInConfig(v, &c, config.WithFile("testdata/test.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Config{
+		Log: Log{
+			Console: true,   // from file
+			Level:   "info", // from file
+			Name:    "default",
+			Custom:  "value", // from file
+		},
+		Server: Server{
+			Port:    8000, // pre-populated value
+			Ratio:   0.5,
+			Timeout: time.Second * 5,
+			Hosts:   []string{"a", "b"},
+			Retries: 3,
+		},
+	}, c)
+	assert.Equal(t, time.Second*5, v.Get("server.timeout"))
+}
+
+func TestReadInConfig_InvalidDefault(t *testing.T) {
+	type Config struct {
+		Port int `default:"port"`
+	}
+	v := config.ViperWithDefaults("defaults")
+	err := config.ReadInConfig(v, &Config{})
+	assert.EqualError(t, err, `invalid default for port: strconv.ParseInt: parsing "port": invalid syntax`)
+}
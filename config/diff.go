@@ -0,0 +1,313 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// A ChangeType describes how the value of a configuration key changed
+type ChangeType string
+
+// Types of configuration changes
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// A Change describes a configuration key which differs between two
+// configurations. Values of secret keys are redacted.
+type Change struct {
+	Key  string      `json:"key"`
+	Type ChangeType  `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Changes lists configuration changes sorted by key
+type Changes []Change
+
+// Log logs each change at info level
+func (c Changes) Log(log zerolog.Logger) {
+	for _, ch := range c {
+		log.Info().
+			Str("key", ch.Key).
+			Str("change", string(ch.Type)).
+			Interface("old", ch.Old).
+			Interface("new", ch.New).
+			Msg("configuration changed")
+	}
+}
+
+// Diff compares two configuration structs key by key, e.g. the old and new
+// configuration passed to a Watcher OnChange func. Map entries are compared
+// by their keys, other values as a whole. Values of fields tagged
+// `secret:"true"`, and values read from encrypted values, are compared but
+// redacted.
+//
+// Example:
+//
+//	w.OnChange(func(old, new *Config) {
+//		config.Diff(old, new).Log(log)
+//	})
+func Diff(old, new interface{}) Changes {
+	return NewSnapshot(old).Diff(NewSnapshot(new))
+}
+
+// A Snapshot records the values of a configuration by key so it can be
+// persisted and compared with a later configuration. Set secret values, and
+// values read from encrypted values or references to them, are recorded as
+// redacted, a digest keyed for the process compares them with
+// snapshots taken by the same process but is never persisted. Changes to
+// secrets are not detected against a snapshot read from a file.
+type Snapshot struct {
+	Values  map[string]interface{} `json:"values"`
+	Secrets map[string]bool        `json:"secrets,omitempty"`
+	digests map[string]string      // secret digests by key
+}
+
+// digestKey keys the digests of secret values, it is random for each
+// process so digests cannot be compared with known values offline
+var digestKey = func() []byte {
+	b := make([]byte, 32)
+	// crypto/rand.Read does not fail on supported platforms
+	_, _ = rand.Read(b)
+	return b
+}()
+
+// NewSnapshot records the values of the configuration struct c
+func NewSnapshot(c interface{}) Snapshot {
+	s := Snapshot{
+		Values:  map[string]interface{}{},
+		Secrets: map[string]bool{},
+		digests: map[string]string{},
+	}
+	secrets := secretKeysOf(c)
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		if isSecret(f) || secrets[strings.ToLower(f.key)] {
+			if !f.value.IsZero() {
+				s.addSecret(f.key, f.value.Interface())
+			}
+			return nil
+		}
+		s.add(f.key, snapshotValue(f.value), secrets)
+		return nil
+	})
+	return s
+}
+
+// add records a value, map entries are recorded by key
+func (s Snapshot) add(key string, val interface{}, secrets map[string]bool) {
+	if m, ok := val.(map[string]interface{}); ok {
+		for k, v := range m {
+			s.add(key+"."+k, v, secrets)
+		}
+		return
+	}
+	if secrets[strings.ToLower(key)] {
+		s.addSecret(key, val)
+		return
+	}
+	s.Values[key] = val
+}
+
+// addSecret records a secret value as redacted along with its digest
+func (s Snapshot) addSecret(key string, val interface{}) {
+	mac := hmac.New(sha256.New, digestKey)
+	mac.Write([]byte(fmt.Sprint(val)))
+	s.Values[key] = redacted
+	s.Secrets[key] = true
+	s.digests[key] = string(mac.Sum(nil))
+}
+
+// secretKeys holds the lowercased keys of each configuration struct type
+// which held decrypted values, or references to them, when read, so
+// snapshots of the type redact them. Keys stay recorded for later reads.
+var secretKeys = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]bool
+}{m: map[reflect.Type]map[string]bool{}}
+
+// recordSecretKeys records keys as holding secrets in configurations of
+// the type of c
+func recordSecretKeys(c interface{}, keys map[string]bool) {
+	if len(keys) == 0 {
+		return
+	}
+	t := reflect.Indirect(reflect.ValueOf(c)).Type()
+	secretKeys.Lock()
+	defer secretKeys.Unlock()
+	if secretKeys.m[t] == nil {
+		secretKeys.m[t] = map[string]bool{}
+	}
+	for k := range keys {
+		secretKeys.m[t][k] = true
+	}
+}
+
+// secretKeysOf returns the keys recorded as holding secrets in
+// configurations of the type of c
+func secretKeysOf(c interface{}) map[string]bool {
+	t := reflect.Indirect(reflect.ValueOf(c)).Type()
+	secretKeys.RLock()
+	defer secretKeys.RUnlock()
+	keys := map[string]bool{}
+	for k := range secretKeys.m[t] {
+		keys[k] = true
+	}
+	return keys
+}
+
+// snapshotValue returns a JSON compatible value which compares equal after
+// a snapshot is written and read. Values with a text form such as
+// time.Duration are recorded as text.
+func snapshotValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	var val interface{}
+	_ = json.Unmarshal(b, &val)
+	return val
+}
+
+// Diff returns the changes from s to next
+func (s Snapshot) Diff(next Snapshot) Changes {
+	display := func(snap Snapshot, key string) interface{} {
+		if snap.Secrets[key] {
+			return redacted
+		}
+		return snap.Values[key]
+	}
+	var changes Changes
+	for k, ov := range s.Values {
+		nv, ok := next.Values[k]
+		od, oh := s.digests[k]
+		nd, nh := next.digests[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: k, Type: ChangeRemoved, Old: display(s, k)})
+		case !reflect.DeepEqual(ov, nv) || (oh && nh && od != nd):
+			changes = append(changes, Change{Key: k, Type: ChangeChanged, Old: display(s, k), New: display(next, k)})
+		}
+	}
+	for k := range next.Values {
+		if _, ok := s.Values[k]; !ok {
+			changes = append(changes, Change{Key: k, Type: ChangeAdded, New: display(next, k)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// ReadSnapshot reads a snapshot written by Snapshot.Write
+func ReadSnapshot(p string) (Snapshot, error) {
+	var s Snapshot
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return s, fmt.Errorf("%s: %v", p, err)
+	}
+	return s, nil
+}
+
+// Write writes the snapshot to the file p as JSON
+func (s Snapshot) Write(p string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(p, b)
+}
+
+// WithChangeLog returns an Option logging the changes between the loaded
+// configuration and the snapshot persisted in the file p by the previous
+// load, e.g. to see drift between deploys. The snapshot is replaced once
+// the configuration is loaded and validated. Snapshot errors are logged as
+// warnings and do not fail loading.
+//
+// Example:
+//
+//	err := config.ReadInConfig(v, &c, config.WithChangeLog(log, "/var/lib/app/config.json"))
+func WithChangeLog(log zerolog.Logger, p string) Option {
+	return withState(func(s *state) {
+		s.changeLog = func(c interface{}) {
+			logChanges(log, p, c)
+		}
+	})
+}
+
+// logChanges logs the changes from the snapshot in p to c and writes the
+// snapshot of c
+func logChanges(log zerolog.Logger, p string, c interface{}) {
+	log = log.With().Str("snapshot", p).Logger()
+	next := NewSnapshot(c)
+	prev, err := ReadSnapshot(p)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Info().Msg("no previous configuration snapshot")
+	case err != nil:
+		log.Warn().Err(err).Msg("error reading configuration snapshot")
+	default:
+		changes := prev.Diff(next)
+		changes.Log(log)
+		if len(changes) == 0 {
+			log.Debug().Msg("configuration unchanged")
+		}
+	}
+	err = next.Write(p)
+	if err != nil {
+		log.Warn().Err(err).Msg("error writing configuration snapshot")
+	}
+}
+
+// writeAtomic writes a file atomically so a partial write is never read. The
+// data is written to a uniquely named temp file in the same directory, so
+// concurrent writers do not share it, which is renamed over p.
+func writeAtomic(p string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
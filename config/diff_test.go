@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+type diffConfig struct {
+	Name    string
+	Pass    string `secret:"true"`
+	Timeout time.Duration
+	Hosts   []string
+	Labels  map[string]string
+}
+
+func TestDiff(t *testing.T) {
+	old := diffConfig{
+		Name:    "kit",
+		Pass:    "old",
+		Timeout: time.Second,
+		Hosts:   []string{"a"},
+		Labels:  map[string]string{"team": "core", "tier": "1"},
+	}
+	new := diffConfig{
+		Name:    "kit",
+		Pass:    "new",
+		Timeout: time.Minute,
+		Hosts:   []string{"a", "b"},
+		Labels:  map[string]string{"team": "web", "env": "prod"},
+	}
+	assert.Equal(t, config.Changes{
+		{Key: "hosts", Type: config.ChangeChanged, Old: []interface{}{"a"}, New: []interface{}{"a", "b"}},
+		{Key: "labels.env", Type: config.ChangeAdded, New: "prod"},
+		{Key: "labels.team", Type: config.ChangeChanged, Old: "core", New: "web"},
+		{Key: "labels.tier", Type: config.ChangeRemoved, Old: "1"},
+		{Key: "pass", Type: config.ChangeChanged, Old: "[REDACTED]", New: "[REDACTED]"},
+		{Key: "timeout", Type: config.ChangeChanged, Old: "1s", New: "1m0s"},
+	}, config.Diff(&old, &new))
+	assert.Empty(t, config.Diff(old, old))
+}
+
+func TestSnapshot_ReadWrite(t *testing.T) {
+	c := diffConfig{
+		Name:    "kit",
+		Pass:    "s3cret",
+		Timeout: time.Second,
+		Hosts:   []string{"a"},
+		Labels:  map[string]string{"team": "core"},
+	}
+	p := filepath.Join(t.TempDir(), "snapshot.json")
+	err := config.NewSnapshot(c).Write(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := config.ReadSnapshot(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, snap.Diff(config.NewSnapshot(c)))
+	assert.Equal(t, "[REDACTED]", snap.Values["pass"], "secrets are not persisted, even hashed")
+
+	// secrets are compared within a process
+	changed := c
+	changed.Pass = "changed"
+	assert.Equal(t, config.Changes{
+		{Key: "pass", Type: config.ChangeChanged, Old: "[REDACTED]", New: "[REDACTED]"},
+	}, config.NewSnapshot(c).Diff(config.NewSnapshot(changed)))
+
+	// the temp file is renamed over the snapshot
+	files, err := os.ReadDir(filepath.Dir(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, files, 1)
+}
+
+func TestWithChangeLog(t *testing.T) {
+	type Config struct {
+		Log struct {
+			Level string
+		}
+	}
+	p := filepath.Join(t.TempDir(), "snapshot.json")
+	buf := &bytes.Buffer{}
+	log := zerolog.New(buf)
+
+	t.Setenv("CHANGELOG_LOG_LEVEL", "info")
+	err := config.ReadInConfig(config.ViperWithDefaults("changelog"), &Config{}, config.WithChangeLog(log, p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), `"message":"no previous configuration snapshot"`)
+
+	buf.Reset()
+	t.Setenv("CHANGELOG_LOG_LEVEL", "debug")
+	err = config.ReadInConfig(config.ViperWithDefaults("changelog"), &Config{}, config.WithChangeLog(log, p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{
+		"level": "info",
+		"snapshot": "`+p+`",
+		"key": "log.level",
+		"change": "changed",
+		"old": "info",
+		"new": "debug",
+		"message": "configuration changed"
+	}`, buf.String())
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// WithDotEnv returns an Option loading env vars from a dotenv file, e.g.
+// `.env`, so they are bound with the same `NAME_FIELD` mapping as other env
+// vars. Env vars set in the environment take precedence over the file, the
+// file takes precedence over config files and sources. Values are held for
+// the read only, the process environment is not modified. Later files
+// override earlier ones, a missing file is ignored.
+//
+// The file holds `NAME=value` lines, optionally prefixed with `export`.
+// Blank lines and lines starting with `#` are ignored, as are comments after
+// unquoted values. Double quoted values may span lines and contain `\n`,
+// `\t`, `\"`, `\\` and `\$` escapes, single quoted values are literal.
+// `$VAR`, `${VAR}` and `${VAR:-default}` are expanded in unquoted and double
+// quoted values from the environment, earlier files or earlier lines.
+//
+// Example:
+//
+//	err := config.ReadInConfig(v, &c, config.WithDotEnv(".env"))
+func WithDotEnv(p string) Option {
+	return withState(func(s *state) {
+		s.dotEnvFiles = append(s.dotEnvFiles, p)
+	})
+}
+
+// A dotEnvVar is a variable loaded from a dotenv file
+type dotEnvVar struct {
+	value string
+	path  string // dotenv file path
+}
+
+// loadDotEnv loads the dotenv files added to the read
+func (s *state) loadDotEnv() error {
+	s.dotEnv = map[string]dotEnvVar{}
+	for _, p := range s.dotEnvFiles {
+		f, err := os.Open(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		vars, err := parseDotEnv(f, s.lookupEnv)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		for _, kv := range vars {
+			s.dotEnv[kv[0]] = dotEnvVar{value: kv[1], path: p}
+		}
+	}
+	return nil
+}
+
+// lookupEnv looks up an env var, falling back to the dotenv files of the
+// read
+func (s *state) lookupEnv(name string) (string, bool) {
+	if val, ok := os.LookupEnv(name); ok {
+		return val, true
+	}
+	d, ok := s.dotEnv[name]
+	return d.value, ok
+}
+
+// environ returns the sorted names of the env vars and dotenv vars starting
+// with prefix
+func (s *state) environ(prefix string) []string {
+	names := environ(prefix)
+	for name := range s.dotEnv {
+		if _, ok := os.LookupEnv(name); !ok && strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dotEnvSettings returns the settings of the configuration struct c set by
+// dotenv vars, as nested maps. Vars set in the environment are left to
+// viper, slices of structs are set by bindIndexedEnvs.
+func (s *state) dotEnvSettings(v *viper.Viper, c interface{}) map[string]interface{} {
+	settings := map[string]interface{}{}
+	set := func(key, name string) {
+		if _, ok := os.LookupEnv(name); ok {
+			return
+		}
+		if d, ok := s.dotEnv[name]; ok {
+			setPath(settings, strings.Split(strings.ToLower(key), "."), d.value)
+		}
+	}
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		switch {
+		case f.value.Kind() == reflect.Map:
+			prefix := envName(v, f.key) + "_"
+			var names []string
+			for name := range s.dotEnv {
+				if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+					names = append(names, name)
+				}
+			}
+			for name, key := range mapEnvKeys(f, prefix, names) {
+				set(key, name)
+			}
+		case isStructSlice(f.value.Type()):
+		default:
+			set(f.key, envName(v, f.key))
+		}
+		return nil
+	})
+	return settings
+}
+
+// parseDotEnv parses dotenv syntax into name value pairs in file order,
+// lookup is used to expand variables not set by earlier lines
+func parseDotEnv(r io.Reader, lookup func(string) (string, bool)) ([][2]string, error) {
+	var vars [][2]string
+	set := map[string]string{}
+	expand := func(s string, escapes bool) string {
+		return expandVars(s, escapes, func(name string) (string, bool) {
+			if val, ok := lookup(name); ok {
+				return val, true
+			}
+			val, ok := set[name]
+			return val, ok
+		})
+	}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		name, val, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validEnvName(name) {
+			return nil, fmt.Errorf("line %d: invalid dotenv line", n)
+		}
+		val = strings.TrimSpace(val)
+		switch {
+		case strings.HasPrefix(val, `"`):
+			// double quoted values may span lines until the closing quote
+			start := n
+			end := quoteEnd(val)
+			for end < 0 {
+				if !sc.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+				}
+				n++
+				val += "\n" + sc.Text()
+				end = quoteEnd(val)
+			}
+			val = expand(val[1:end], true)
+		case strings.HasPrefix(val, "'"):
+			end := strings.IndexByte(val[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", n)
+			}
+			val = val[1 : end+1]
+		default:
+			if i := strings.Index(val, " #"); i >= 0 {
+				val = strings.TrimSpace(val[:i])
+			}
+			val = expand(val, false)
+		}
+		set[name] = val
+		vars = append(vars, [2]string{name, val})
+	}
+	return vars, sc.Err()
+}
+
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteEnd returns the index of the unescaped closing quote of a double
+// quoted value, or -1 if it is not closed
+func quoteEnd(val string) int {
+	for i := 1; i < len(val); i++ {
+		switch val[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// dotEnvEscapes are the escapes supported in double quoted values
+var dotEnvEscapes = map[byte]byte{'n': '\n', 't': '\t', '"': '"', '\\': '\\', '$': '$'}
+
+// expandVars expands `$VAR`, `${VAR}` and `${VAR:-default}` references,
+// replacing backslash escapes if escapes is true
+func expandVars(s string, escapes bool, lookup func(string) (string, bool)) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\' && i+1 < len(s) && dotEnvEscapes[s[i+1]] != 0:
+			b.WriteByte(dotEnvEscapes[s[i+1]])
+			i++
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			name, def, hasDef := strings.Cut(s[i+2:i+end], ":-")
+			val, ok := lookup(name)
+			if (!ok || val == "") && hasDef {
+				val = def
+			}
+			b.WriteString(val)
+			i += end
+		case s[i] == '$':
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z' || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte('$')
+				continue
+			}
+			val, _ := lookup(s[i+1 : j])
+			b.WriteString(val)
+			i = j - 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestWithDotEnv(t *testing.T) {
+	type Config struct {
+		Name    string
+		Host    string
+		URL     string `mapstructure:"url"`
+		Literal string
+		Multi   string
+		Real    string
+		Missing string
+		Count   int
+		Labels  map[string]string
+	}
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "dotenv.toml")
+	writeFile(t, fp, "count = 5\nname = \"file\"\n")
+	p := filepath.Join(dir, ".env")
+	writeFile(t, p, `
+# local development
+export DOTENV_NAME=dotenv # trailing comment
+DOTENV_HOST="localhost"
+DOTENV_URL="http://${DOTENV_HOST}:${DOTENV_PORT:-8000}/\$path"
+DOTENV_LITERAL='${DOTENV_HOST} # not a comment'
+DOTENV_MULTI="a
+b\tc"
+DOTENV_REAL=file
+DOTENV_MISSING=$DOTENV_UNSET
+DOTENV_COUNT=6
+DOTENV_LABELS_TEAM=platform
+`)
+	t.Setenv("DOTENV_REAL", "env")
+	for _, name := range []string{"DOTENV_NAME", "DOTENV_HOST", "DOTENV_URL", "DOTENV_LITERAL", "DOTENV_MULTI", "DOTENV_MISSING"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	var c Config
+	err := config.ReadInConfig(config.ViperWithDefaults("dotenv"), &c, config.WithFile(fp), config.WithDotEnv(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Config{
+		Name:    "dotenv",
+		Host:    "localhost",
+		URL:     "http://localhost:8000/$path",
+		Literal: "${DOTENV_HOST} # not a comment",
+		Multi:   "a\nb\tc",
+		Real:    "env",
+		Count:   6,
+		Labels:  map[string]string{"team": "platform"},
+	}, c)
+	_, ok := os.LookupEnv("DOTENV_NAME")
+	assert.False(t, ok, "the process environment is not modified")
+
+	// changes to the file replace previously loaded values
+	writeFile(t, p, "DOTENV_NAME=changed\n")
+	c = Config{}
+	err = config.ReadInConfig(config.ViperWithDefaults("dotenv"), &c, config.WithDotEnv(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Config{Name: "changed", Real: "env"}, c)
+
+	// later files override earlier ones
+	p2 := filepath.Join(dir, ".env.local")
+	writeFile(t, p2, "DOTENV_NAME=local\n")
+	err = config.ReadInConfig(config.ViperWithDefaults("dotenv"), &c, config.WithDotEnv(p), config.WithDotEnv(p2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "local", c.Name)
+
+	// unknown dotenv vars are reported with the file they are set in
+	writeFile(t, p2, "DOTENV_NAEM=local\n")
+	err = config.ReadInConfig(config.ViperWithDefaults("dotenv"), &c, config.WithDotEnv(p2), config.StrictEnv(nil))
+	var uerr *config.UnknownKeysError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected UnknownKeysError, got %v", err)
+	}
+	assert.Equal(t, []config.UnknownKey{{Key: "DOTENV_NAEM", Origin: p2, Suggestion: "DOTENV_NAME"}}, uerr.Keys)
+
+	// missing files are ignored
+	err = config.ReadInConfig(config.ViperWithDefaults("dotenv"), &c, config.WithDotEnv(p+".missing"))
+	assert.NoError(t, err)
+}
+
+func TestWithDotEnv_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing equals": "DOTENV_NAME\n",
+		"invalid name":   "1NAME=value\n",
+		"unterminated":   "DOTENV_NAME=\"value\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), ".env")
+			writeFile(t, p, data)
+			var c struct{ Name string }
+			err := config.ReadInConfig(config.ViperWithDefaults("dotenv"), &c, config.WithDotEnv(p))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Encrypted values are written as `ENC[v1,<base64>]`, the base64 data is a
+// random nonce followed by the AES-256-GCM sealed value
+const (
+	encPrefix  = "ENC[v1,"
+	encSuffix  = "]"
+	encKeySize = 32
+)
+
+// ErrNoKey is returned when a configuration holds encrypted values but no
+// decryption key is configured
+var ErrNoKey = errors.New("encrypted value but no decryption key, set one with WithKeyFile or WithKeyEnv")
+
+// WithKeyFile returns an Option decrypting `ENC[...]` values with the base64
+// encoded key held in the file p. The key is only read if the configuration
+// holds encrypted values.
+func WithKeyFile(p string) Option {
+	return withState(func(s *state) {
+		s.key = func() (string, error) {
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return "", fmt.Errorf("decryption key file: %v", err)
+			}
+			return string(b), nil
+		}
+	})
+}
+
+// WithKeyEnv returns an Option decrypting `ENC[...]` values with the base64
+// encoded key held in the env var name. The key is only read if the
+// configuration holds encrypted values.
+func WithKeyEnv(name string) Option {
+	return withState(func(s *state) {
+		s.key = func() (string, error) {
+			k, ok := os.LookupEnv(name)
+			if !ok || k == "" {
+				return "", fmt.Errorf("decryption key env var %s is not set", name)
+			}
+			return k, nil
+		}
+	})
+}
+
+// GenerateKey returns a new random base64 encoded key for Encrypt and
+// WithKeyFile or WithKeyEnv
+func GenerateKey() (string, error) {
+	b := make([]byte, encKeySize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Encrypt encrypts a value with a base64 encoded key returning an
+// `ENC[...]` value for a config file. Encrypted values are decrypted by
+// ReadInConfig and ReadInAllDirConfig with the key set by WithKeyFile or
+// WithKeyEnv.
+//
+// Example:
+//
+//	enc, err := config.Encrypt(key, "s3cret")
+//	// pass = "ENC[v1,...]"
+func Encrypt(key, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	data := aead.Seal(nonce, nonce, []byte(value), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(data) + encSuffix, nil
+}
+
+// decrypt decrypts an `ENC[...]` value
+func decrypt(key, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encPrefix), encSuffix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	b, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("decryption failed, wrong key or corrupt value")
+	}
+	return string(b), nil
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	k, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %v", err)
+	}
+	if len(k) != encKeySize {
+		return nil, fmt.Errorf("invalid key: must be %d bytes", encKeySize)
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncrypted returns true for `ENC[...]` values
+func isEncrypted(s string) bool {
+	return strings.HasPrefix(s, encPrefix) && strings.HasSuffix(s, encSuffix)
+}
+
+// decryptSettings decrypts the `ENC[...]` string values of settings with the
+// key returned by load, if set, returning the keys holding decrypted values
+func decryptSettings(settings map[string]interface{}, load func() (string, error)) (map[string]bool, error) {
+	var (
+		key  string
+		kerr error
+		errs []error
+	)
+	loaded := false
+	decrypted := map[string]bool{}
+	dec := func(k, s string) string {
+		if !isEncrypted(s) {
+			return s
+		}
+		decrypted[k] = true
+		if !loaded {
+			loaded = true
+			kerr = ErrNoKey
+			if load != nil {
+				key, kerr = load()
+			}
+		}
+		if kerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k, kerr))
+			return s
+		}
+		pt, err := decrypt(key, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", k, err))
+			return s
+		}
+		return pt
+	}
+	for _, k := range leafKeys(settings, "") {
+		switch val := lookupPath(settings, k).(type) {
+		case string:
+			if isEncrypted(val) {
+				setPath(settings, strings.Split(k, "."), dec(k, val))
+			}
+		case []interface{}:
+			items := make([]interface{}, len(val))
+			for i, item := range val {
+				items[i] = item
+				if s, ok := item.(string); ok {
+					items[i] = dec(k, s)
+				}
+			}
+			setPath(settings, strings.Split(k, "."), items)
+		}
+	}
+	return decrypted, errors.Join(errs...)
+}
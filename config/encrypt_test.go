@@ -0,0 +1,164 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestReadInConfig_Encrypted(t *testing.T) {
+	type Config struct {
+		User string
+		Pass string `secret:"true"`
+		Keys []string
+	}
+	key, err := config.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass, err := config.Encrypt(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := config.Encrypt(key, "api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(pass, "ENC["))
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, "enc.toml")
+	writeFile(t, p, `
+user = "app"
+pass = "`+pass+`"
+keys = ["`+apiKey+`", "plain"]
+`)
+	keyFile := filepath.Join(dir, "key")
+	writeFile(t, keyFile, key+"\n")
+	want := Config{User: "app", Pass: "s3cret", Keys: []string{"api-key", "plain"}}
+
+	var c Config
+	err = config.ReadInConfig(config.ViperWithDefaults("enc"), &c, config.WithFile(p), config.WithKeyFile(keyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, want, c)
+
+	t.Setenv("ENC_KEY", key)
+	c = Config{}
+	err = config.ReadInConfig(config.ViperWithDefaults("enc"), &c, config.WithFile(p), config.WithKeyEnv("ENC_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, want, c)
+}
+
+func TestReadInConfig_EncryptedErrors(t *testing.T) {
+	type Config struct {
+		Pass string
+	}
+	key, _ := config.GenerateKey()
+	other, _ := config.GenerateKey()
+	pass, err := config.Encrypt(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "encerr.toml")
+	writeFile(t, p, `pass = "`+pass+`"`)
+	t.Setenv("ENCERR_OTHER_KEY", other)
+
+	var c Config
+	err = config.ReadInConfig(config.ViperWithDefaults("encerr"), &c, config.WithFile(p))
+	assert.True(t, errors.Is(err, config.ErrNoKey), "missing key")
+	assert.EqualError(t, config.ReadInConfig(config.ViperWithDefaults("encerr"), &c,
+		config.WithFile(p), config.WithKeyEnv("ENCERR_UNSET")),
+		"pass: decryption key env var ENCERR_UNSET is not set")
+	assert.EqualError(t, config.ReadInConfig(config.ViperWithDefaults("encerr"), &c,
+		config.WithFile(p), config.WithKeyEnv("ENCERR_OTHER_KEY")),
+		"pass: decryption failed, wrong key or corrupt value")
+	assert.Error(t, config.ReadInConfig(config.ViperWithDefaults("encerr"), &c,
+		config.WithFile(p), config.WithKeyFile(filepath.Join(t.TempDir(), "missing"))))
+}
+
+func TestReadInConfig_EncryptedRedacted(t *testing.T) {
+	type DB struct {
+		Pass string
+		URL  string
+		Host string
+	}
+	type Config struct {
+		DB     DB `mapstructure:"db"`
+		Labels map[string]string
+	}
+	key, err := config.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass, err := config.Encrypt(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	p := filepath.Join(dir, "redact.toml")
+	writeFile(t, p, `
+[db]
+pass = "`+pass+`"
+url = "postgres://u:${db.pass}@${db.host}"
+host = "h"
+
+[labels]
+token = "`+pass+`"
+team = "platform"
+`)
+	keyFile := filepath.Join(dir, "key")
+	writeFile(t, keyFile, key)
+	snapshot := filepath.Join(dir, "snapshot.json")
+
+	var c Config
+	var r config.Report
+	err = config.ReadInConfig(config.ViperWithDefaults("redact"), &c,
+		config.WithFile(p),
+		config.WithKeyFile(keyFile),
+		config.WithReport(&r),
+		config.WithChangeLog(zerolog.Nop(), snapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// references to encrypted keys expand to the decrypted value
+	assert.Equal(t, "postgres://u:s3cret@h", c.DB.URL)
+	assert.Equal(t, "s3cret", c.DB.Pass)
+	assert.Equal(t, "s3cret", c.Labels["token"])
+
+	b, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, string(b), "s3cret")
+	snap, err := config.ReadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "h", snap.Values["db.host"])
+	assert.Equal(t, "platform", snap.Values["labels.team"])
+
+	var buf bytes.Buffer
+	if err := r.Explain(&c).WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, buf.String(), "s3cret")
+	assert.Contains(t, buf.String(), "[REDACTED]")
+
+	next := c
+	next.DB.Pass = "changed"
+	changes := config.Diff(&c, &next)
+	assert.Equal(t, config.Changes{
+		{Key: "db.pass", Type: config.ChangeChanged, Old: "[REDACTED]", New: "[REDACTED]"},
+	}, changes)
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/viper"
+)
+
+// A Source identifies where the value of a configuration key came from
+type Source string
+
+// Sources of configuration values, in increasing order of precedence
+const (
+	SourceDefault Source = "default"
+	SourcePreset  Source = "preset" // held by the struct before loading
+	SourceFile    Source = "file"
+	SourceRemote  Source = "remote"
+	SourceDotEnv  Source = "dotenv"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// redacted replaces the value of secret fields
+const redacted = "[REDACTED]"
+
+// A Setting describes the effective value of a configuration key
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+	// Origin names the file, remote source, env var or flag which supplied
+	// the value, dotenv vars are named `path:NAME`
+	Origin string `json:"origin,omitempty"`
+}
+
+// Settings lists the effective configuration
+type Settings []Setting
+
+// A Report describes the files, sources, env vars and flags used by a read,
+// it is filled in by passing WithReport to ReadInConfig or
+// ReadInAllDirConfig
+type Report struct {
+	v     *viper.Viper
+	state *state
+}
+
+// WithReport returns an Option filling in r once the configuration has been
+// read
+//
+// Example:
+//
+//	var r config.Report
+//	err := config.ReadInConfig(v, &c, config.WithReport(&r))
+//	...
+//	err = r.Explain(&c).WriteTable(os.Stdout)
+func WithReport(r *Report) Option {
+	return withState(func(s *state) {
+		s.report = r
+	})
+}
+
+// Explain returns every key of the configuration struct c, along with its
+// value and the source which supplied it. Values of fields tagged
+// `secret:"true"`, and values read from encrypted values or references to
+// them, are redacted. Nil is returned if the read failed before
+// loading the configuration.
+func (r *Report) Explain(c interface{}) Settings {
+	if r.state == nil {
+		return nil
+	}
+	var settings Settings
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		src, origin := r.state.source(r.v, f.key, hasEntries(f))
+		settings = append(settings, Setting{
+			Key:    f.key,
+			Value:  r.state.displayValue(f),
+			Source: src,
+			Origin: origin,
+		})
+		return nil
+	})
+	return settings
+}
+
+// Layers returns the config files which were read in the order they were
+// merged
+func (r *Report) Layers() []string {
+	if r.state == nil {
+		return nil
+	}
+	var layers []string
+	for _, f := range r.state.files {
+		layers = append(layers, f.path)
+	}
+	return layers
+}
+
+// WriteJSON writes the settings as a JSON array
+func (s Settings) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteTable writes the settings as a human-readable table
+func (s Settings) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, setting := range s {
+		src := string(setting.Source)
+		if setting.Origin != "" {
+			src = fmt.Sprintf("%s (%s)", src, setting.Origin)
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", setting.Key, setting.Value, src)
+	}
+	return tw.Flush()
+}
+
+// source returns the source of the value of a key following viper's
+// precedence; changed flags, env vars, dotenv files, remote sources, config
+// files, values preset in the struct then defaults. The entries of map and
+// slice fields, with entries true, may be set by prefixed env vars e.g.
+// NAME_LABELS_TEAM.
+func (s *state) source(v *viper.Viper, key string, entries bool) (Source, string) {
+	lk := strings.ToLower(key)
+	if flag, ok := s.flags[lk]; ok && flag.Changed {
+		return SourceFlag, "--" + flag.Name
+	}
+	env := envName(v, key)
+	names := []string{env}
+	if entries {
+		names = append(names, s.environ(env+"_")...)
+	}
+	var envs, dotEnvs []string
+	for _, name := range names {
+		if val, ok := os.LookupEnv(name); ok {
+			if val != "" {
+				envs = append(envs, name)
+			}
+			continue
+		}
+		if d, ok := s.dotEnv[name]; ok {
+			dotEnvs = append(dotEnvs, d.path+":"+name)
+		}
+	}
+	switch {
+	case len(envs) > 0:
+		return SourceEnv, strings.Join(envs, ", ")
+	case len(dotEnvs) > 0:
+		return SourceDotEnv, strings.Join(dotEnvs, ", ")
+	}
+	// later sources and files override earlier ones
+	for i := len(s.sourced) - 1; i >= 0; i-- {
+		if hasKey(s.sourced[i].keys, lk, entries) {
+			return SourceRemote, s.sourced[i].name
+		}
+	}
+	for i := len(s.files) - 1; i >= 0; i-- {
+		if hasKey(s.files[i].keys, lk, entries) {
+			return SourceFile, s.files[i].path
+		}
+	}
+	if s.preset[lk] {
+		return SourcePreset, ""
+	}
+	return SourceDefault, ""
+}
+
+// hasKey returns true if keys holds key, or with entries a key nested in it
+func hasKey(keys map[string]bool, key string, entries bool) bool {
+	if keys[key] {
+		return true
+	}
+	if entries {
+		for k := range keys {
+			if strings.HasPrefix(k, key+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordPresets records the keys of the fields of c which hold a value
+// before it is read
+func (s *state) recordPresets(c interface{}) {
+	s.preset = map[string]bool{}
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		if !f.value.IsZero() {
+			s.preset[strings.ToLower(f.key)] = true
+		}
+		return nil
+	})
+}
+
+// hasEntries returns true for fields whose entries are bound to env vars by
+// key or index
+func hasEntries(f field) bool {
+	return f.value.Kind() == reflect.Map || isStructSlice(f.value.Type())
+}
+
+// displayValue returns a field value for display, redacting secrets. Maps
+// and slices holding a secret entry are redacted as a whole.
+func (s *state) displayValue(f field) interface{} {
+	secret := hasKey(s.secretKeys, strings.ToLower(f.key), hasEntries(f))
+	if (isSecret(f) || secret) && !f.value.IsZero() {
+		return redacted
+	}
+	if s, ok := f.value.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return f.value.Interface()
+}
+
+// A configFile records the keys set by a config file
+type configFile struct {
+	path string
+	keys map[string]bool
+}
+
+// recordFile records the keys set by a config file so the source of values
+// can be explained
+func (s *state) recordFile(p string, keys []string) {
+	s.files = append(s.files, configFile{path: p, keys: keySet(keys)})
+}
+
+func keySet(keys []string) map[string]bool {
+	set := map[string]bool{}
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
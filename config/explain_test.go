@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestExplain(t *testing.T) {
+	type Log struct {
+		Verbose bool
+		Console bool
+		Level   string
+		Name    string
+		Custom  string        `mapstructure:"customTag"`
+		Timeout time.Duration `default:"5s"`
+		Token   string        `secret:"true"`
+		Sink    string
+	}
+	type Config struct {
+		Log    Log
+		Labels map[string]string
+		Tags   map[string]string
+	}
+
+	t.Setenv("EXPLAIN_LOG_LEVEL", "error")
+	t.Setenv("EXPLAIN_LABELS_TEAM", "platform")
+	t.Setenv("EXPLAIN_LOG_TOKEN", "s3cret")
+	fs := pflag.NewFlagSet("explain", pflag.ContinueOnError)
+	fs.String("name", "blah", "")
+	if err := fs.Parse([]string{"--name=flag"}); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.toml"), "[log]\nconsole = true\ncustomTag = \"a\"\n\n[tags]\nx = \"y\"\n")
+	writeFile(t, filepath.Join(dir, "b.toml"), "[log]\ncustomTag = \"b\"\n")
+
+	dotEnv := filepath.Join(t.TempDir(), ".env")
+	writeFile(t, dotEnv, "EXPLAIN_LOG_SINK=stdout\n")
+
+	c := Config{Log: Log{Verbose: true}}
+	v, _ := config.ViperWithDir("explain")
+	v.AddConfigPath(dir)
+	var r config.Report
+	err := config.ReadInAllDirConfig(v, dir, &c,
+		config.BindFlag("log.name", fs.Lookup("name")),
+		config.WithDotEnv(dotEnv),
+		config.WithReport(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := r.Explain(&c)
+	assert.Equal(t, config.Settings{
+		{Key: "log.verbose", Value: true, Source: config.SourcePreset},
+		{Key: "log.console", Value: true, Source: config.SourceFile, Origin: filepath.Join(dir, "a.toml")},
+		{Key: "log.level", Value: "error", Source: config.SourceEnv, Origin: "EXPLAIN_LOG_LEVEL"},
+		{Key: "log.name", Value: "flag", Source: config.SourceFlag, Origin: "--name"},
+		{Key: "log.customTag", Value: "b", Source: config.SourceFile, Origin: filepath.Join(dir, "b.toml")},
+		{Key: "log.timeout", Value: "5s", Source: config.SourceDefault},
+		{Key: "log.token", Value: "[REDACTED]", Source: config.SourceEnv, Origin: "EXPLAIN_LOG_TOKEN"},
+		{Key: "log.sink", Value: "stdout", Source: config.SourceDotEnv, Origin: dotEnv + ":EXPLAIN_LOG_SINK"},
+		{Key: "labels", Value: map[string]string{"team": "platform"}, Source: config.SourceEnv, Origin: "EXPLAIN_LABELS_TEAM"},
+		{Key: "tags", Value: map[string]string{"x": "y"}, Source: config.SourceFile, Origin: filepath.Join(dir, "a.toml")},
+	}, settings)
+
+	var buf bytes.Buffer
+	if err := settings.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"key":    "log.level",
+		"value":  "error",
+		"source": "env",
+		"origin": "EXPLAIN_LOG_LEVEL",
+	}, out[2])
+	assert.NotContains(t, buf.String(), "s3cret")
+
+	buf.Reset()
+	if err := settings.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 11)
+	assert.Equal(t, []string{"KEY", "VALUE", "SOURCE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"log.name", "flag", "flag", "(--name)"}, strings.Fields(lines[4]))
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// RegisterFlags registers a flag on fs for every leaf field of the struct c
+// and returns the Options binding them to their configuration keys. Flags
+// are named by the dotted configuration key e.g. `--log.level`, typed by
+// the field type and described by the `desc` struct tag. The flag default is
+// the value already held by the field or its `default` struct tag. Flags
+// already registered on fs are bound as they are, fields of unsupported
+// types or tagged `flag:"-"` are skipped.
+//
+// Example:
+//
+//	opts := config.RegisterFlags(cmd.Flags(), &c)
+//	err := config.ReadInConfig(v, &c, opts...)
+func RegisterFlags(fs *pflag.FlagSet, c interface{}) []Option {
+	var opts []Option
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		if f.tag.Get("flag") == "-" {
+			return nil
+		}
+		if fs.Lookup(f.key) == nil && !addFlag(fs, f) {
+			return nil
+		}
+		opts = append(opts, BindFlag(f.key, fs.Lookup(f.key)))
+		return nil
+	})
+	return opts
+}
+
+// addFlag registers a typed flag for a field, returning false if the field
+// type is not supported
+func addFlag(fs *pflag.FlagSet, f field) bool {
+	name, usage := f.key, f.tag.Get("desc")
+	def := f.value
+	if d, ok := f.tag.Lookup("default"); ok && def.IsZero() {
+		val, err := parseValue(def.Type(), d)
+		if err == nil {
+			def = val
+		}
+	}
+	if def.Type() == durationType {
+		fs.Duration(name, def.Interface().(time.Duration), usage)
+		return true
+	}
+	if isLeaf(def.Type()) {
+		// parsed by a decode hook when unmarshalled
+		s := ""
+		if !def.IsZero() {
+			s = fmt.Sprint(def.Interface())
+		}
+		fs.String(name, s, usage)
+		return true
+	}
+	if isStructSlice(def.Type()) {
+		return false
+	}
+	switch def.Kind() {
+	case reflect.String:
+		fs.String(name, def.String(), usage)
+	case reflect.Bool:
+		fs.Bool(name, def.Bool(), usage)
+	case reflect.Int:
+		fs.Int(name, int(def.Int()), usage)
+	case reflect.Int8:
+		fs.Int8(name, int8(def.Int()), usage)
+	case reflect.Int16:
+		fs.Int16(name, int16(def.Int()), usage)
+	case reflect.Int32:
+		fs.Int32(name, int32(def.Int()), usage)
+	case reflect.Int64:
+		fs.Int64(name, def.Int(), usage)
+	case reflect.Uint:
+		fs.Uint(name, uint(def.Uint()), usage)
+	case reflect.Uint8:
+		fs.Uint8(name, uint8(def.Uint()), usage)
+	case reflect.Uint16:
+		fs.Uint16(name, uint16(def.Uint()), usage)
+	case reflect.Uint32:
+		fs.Uint32(name, uint32(def.Uint()), usage)
+	case reflect.Uint64:
+		fs.Uint64(name, def.Uint(), usage)
+	case reflect.Float32:
+		fs.Float32(name, float32(def.Float()), usage)
+	case reflect.Float64:
+		fs.Float64(name, def.Float(), usage)
+	case reflect.Slice:
+		// viper only decodes string slice flag values, other element types
+		// are converted from strings when unmarshalled
+		items := make([]string, def.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(def.Index(i).Interface())
+		}
+		fs.StringSlice(name, items, usage)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,269 @@
+// Package flags provides feature flags configured with the config package.
+// Flags are declared as a map of Flag in a configuration struct, so they are
+// loaded from config files, sources and env vars like any other setting and
+// are re-evaluated when a config.Watcher reloads.
+//
+// Example config file:
+//
+//	[flags.new_checkout]
+//	enabled = true
+//	rollout = 25
+//	allow = ["user-1"]
+//
+//	[flags.search_ranking]
+//	enabled = true
+//	variants = { control = 50, bm25 = 50 }
+package flags
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.soon.build/kit/config"
+)
+
+// A Flag configures a feature flag. A flag is evaluated for a user ID in
+// order:
+//  1. users in Deny are off
+//  2. users in Allow are on
+//  3. all other users are off unless Enabled
+//  4. if Rollout is set only that percentage of users are on, users are
+//     bucketed by a hash of the flag name and user ID so a user keeps the
+//     same result as the rollout grows
+type Flag struct {
+	Enabled bool     `mapstructure:"enabled" json:"enabled" desc:"turn the flag on"`
+	Rollout *float64 `mapstructure:"rollout" json:"rollout,omitempty" desc:"percentage of users the flag is on for, all when unset"`
+	Allow   []string `mapstructure:"allow" json:"allow,omitempty" desc:"user IDs the flag is always on for"`
+	Deny    []string `mapstructure:"deny" json:"deny,omitempty" desc:"user IDs the flag is always off for"`
+	// Variant is returned by Set.Variant for users the flag is on for
+	Variant string `mapstructure:"variant" json:"variant,omitempty" desc:"variant for users the flag is on for"`
+	// Variants split the users the flag is on for between named variants
+	// by relative weight, overriding Variant
+	Variants map[string]float64 `mapstructure:"variants" json:"variants,omitempty" desc:"variant weights for users the flag is on for"`
+}
+
+// Config holds flags by name, for use as a field of a configuration struct
+//
+// Example:
+//
+//	type Config struct {
+//		Flags flags.Config `mapstructure:"flags"`
+//	}
+type Config map[string]Flag
+
+// A Set evaluates flags, it is safe for concurrent use
+type Set struct {
+	mu        sync.RWMutex // protects flags and overrides
+	flags     Config
+	overrides Config
+}
+
+// New constructs a Set evaluating the flags in c
+func New(c Config) *Set {
+	s := &Set{overrides: Config{}}
+	s.Update(c)
+	return s
+}
+
+// Watch constructs a Set evaluating the flags of the configuration held by
+// w, the flags are updated whenever w reloads a changed configuration
+//
+// Example:
+//
+//	w, err := config.NewWatcher[Config](newViper)
+//	...
+//	fs := flags.Watch(w, func(c *Config) flags.Config {
+//		return c.Flags
+//	})
+//	if fs.Enabled("new_checkout", userID) {
+//		...
+//	}
+func Watch[T any](w *config.Watcher[T], fn func(c *T) Config) *Set {
+	s := New(fn(w.Get()))
+	w.OnChange(func(old, new *T) {
+		s.Update(fn(new))
+	})
+	return s
+}
+
+// Update replaces the flags evaluated by s
+func (s *Set) Update(c Config) {
+	flags := make(Config, len(c))
+	for name, f := range c {
+		flags[strings.ToLower(name)] = f
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = flags
+}
+
+// lookup returns a flag by name, overrides take precedence
+func (s *Set) lookup(name string) (Flag, bool) {
+	name = strings.ToLower(name)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if f, ok := s.overrides[name]; ok {
+		return f, true
+	}
+	f, ok := s.flags[name]
+	return f, ok
+}
+
+// Enabled returns true if the named flag is on for a user ID, unknown
+// flags are off. userID may be empty for flags without rollout or lists.
+func (s *Set) Enabled(name, userID string) bool {
+	f, ok := s.lookup(name)
+	return ok && f.on(name, userID)
+}
+
+// Variant returns the variant of the named flag for a user ID, or fallback
+// if the flag is off for the user or has no variant
+func (s *Set) Variant(name, userID, fallback string) string {
+	f, ok := s.lookup(name)
+	if !ok || !f.on(name, userID) {
+		return fallback
+	}
+	if v := f.pick(name, userID); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// on evaluates f for a user ID
+func (f Flag) on(name, userID string) bool {
+	if contains(f.Deny, userID) {
+		return false
+	}
+	if contains(f.Allow, userID) {
+		return true
+	}
+	if !f.Enabled {
+		return false
+	}
+	if f.Rollout == nil {
+		return true
+	}
+	return bucket(name, userID) < *f.Rollout
+}
+
+// pick returns the variant for a user ID
+func (f Flag) pick(name, userID string) string {
+	if len(f.Variants) == 0 {
+		return f.Variant
+	}
+	names := make([]string, 0, len(f.Variants))
+	total := 0.0
+	for v, w := range f.Variants {
+		if w > 0 {
+			names = append(names, v)
+			total += w
+		}
+	}
+	sort.Strings(names)
+	// bucketed separately from the rollout so variants are spread evenly
+	// across users the flag is on for
+	b := bucket(name+"/variant", userID) / 100 * total
+	for _, v := range names {
+		b -= f.Variants[v]
+		if b < 0 {
+			return v
+		}
+	}
+	return f.Variant
+}
+
+// bucket hashes a flag name and user ID to a percentage in [0, 100)
+func bucket(name, userID string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(name) + ":" + userID))
+	return float64(h.Sum32()%10000) / 100
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// State describes the current configuration of a flag
+type State struct {
+	Name       string `json:"name"`
+	Flag       Flag   `json:"flag"`
+	Overridden bool   `json:"overridden,omitempty"`
+}
+
+// State returns the current configuration of every flag sorted by name,
+// e.g. for a debug endpoint
+func (s *Set) State() []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var states []State
+	for name, f := range s.flags {
+		if _, ok := s.overrides[name]; !ok {
+			states = append(states, State{Name: name, Flag: f})
+		}
+	}
+	for name, f := range s.overrides {
+		states = append(states, State{Name: name, Flag: f, Overridden: true})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// Handler returns a handler writing the State of every flag as JSON. If a
+// `user` query parameter is given the result of each flag for that user ID
+// is included.
+func (s *Set) Handler() http.Handler {
+	type flagState struct {
+		State
+		Enabled *bool   `json:"enabled,omitempty"`
+		Variant *string `json:"variant,omitempty"`
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states := s.State()
+		rsp := make([]flagState, len(states))
+		user := r.URL.Query().Get("user")
+		for i, st := range states {
+			rsp[i].State = st
+			if user != "" {
+				on := st.Flag.on(st.Name, user)
+				v := ""
+				if on {
+					v = st.Flag.pick(st.Name, user)
+				}
+				rsp[i].Enabled, rsp[i].Variant = &on, &v
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rsp)
+	})
+}
+
+// Override replaces the named flag until the returned func is called,
+// overrides are kept when the flags are updated. In tests use
+// flagstest.Override.
+func (s *Set) Override(name string, f Flag) (restore func()) {
+	name = strings.ToLower(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.overrides[name]
+	s.overrides[name] = f
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if ok {
+			s.overrides[name] = prev
+			return
+		}
+		delete(s.overrides, name)
+	}
+}
@@ -0,0 +1,175 @@
+package flags_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+	"go.soon.build/kit/config/configtest"
+	"go.soon.build/kit/config/flags"
+)
+
+type testConfig struct {
+	Flags flags.Config `mapstructure:"flags"`
+}
+
+func rollout(p float64) *float64 {
+	return &p
+}
+
+func TestSet_Enabled(t *testing.T) {
+	s := flags.New(flags.Config{
+		"on":       {Enabled: true},
+		"off":      {},
+		"allowed":  {Allow: []string{"a"}},
+		"denied":   {Enabled: true, Deny: []string{"a"}},
+		"rollout0": {Enabled: true, Rollout: rollout(0), Allow: []string{"b"}},
+	})
+	tcs := map[string]struct {
+		name string
+		user string
+		xOn  bool
+	}{
+		"on":                    {name: "on", user: "a", xOn: true},
+		"off":                   {name: "off", user: "a"},
+		"unknown":               {name: "unknown", user: "a"},
+		"allow list":            {name: "allowed", user: "a", xOn: true},
+		"not in allow list":     {name: "allowed", user: "b"},
+		"deny list":             {name: "denied", user: "a"},
+		"not in deny list":      {name: "denied", user: "b", xOn: true},
+		"zero rollout":          {name: "rollout0", user: "a"},
+		"zero rollout allowed":  {name: "rollout0", user: "b", xOn: true},
+		"case insensitive name": {name: "ON", user: "a", xOn: true},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.xOn, s.Enabled(tc.name, tc.user))
+		})
+	}
+}
+
+func TestSet_Rollout(t *testing.T) {
+	s := flags.New(flags.Config{
+		"half": {Enabled: true, Rollout: rollout(50)},
+	})
+	var on []string
+	for i := 0; i < 1000; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		enabled := s.Enabled("half", user)
+		if enabled {
+			on = append(on, user)
+		}
+		// stable for a user
+		assert.Equal(t, enabled, s.Enabled("half", user))
+	}
+	assert.InDelta(t, 500, len(on), 75)
+	// growing the rollout keeps users on
+	s.Update(flags.Config{
+		"half": {Enabled: true, Rollout: rollout(75)},
+	})
+	for _, user := range on {
+		assert.True(t, s.Enabled("half", user))
+	}
+}
+
+func TestSet_Variant(t *testing.T) {
+	s := flags.New(flags.Config{
+		"single": {Enabled: true, Variant: "blue"},
+		"split":  {Enabled: true, Variants: map[string]float64{"a": 1, "b": 1}},
+		"off":    {Variant: "blue"},
+	})
+	assert.Equal(t, "blue", s.Variant("single", "u", "red"))
+	assert.Equal(t, "red", s.Variant("off", "u", "red"))
+	assert.Equal(t, "red", s.Variant("unknown", "u", "red"))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[s.Variant("split", fmt.Sprintf("user-%d", i), "")]++
+	}
+	assert.Len(t, counts, 2)
+	assert.InDelta(t, 500, counts["a"], 75)
+}
+
+func TestLoad(t *testing.T) {
+	c := configtest.Load[testConfig](t, "flagtest",
+		configtest.WithTOML(`
+[flags.new_checkout]
+enabled = true
+rollout = 0
+allow = ["user-1"]
+
+[flags.ranking]
+enabled = true
+variants = { control = 1 }
+`),
+		configtest.WithEnv("FLAGS_DARK_MODE_ENABLED", "true"),
+	)
+	s := flags.New(c.Flags)
+	assert.True(t, s.Enabled("new_checkout", "user-1"))
+	assert.False(t, s.Enabled("new_checkout", "user-2"))
+	assert.True(t, s.Enabled("dark_mode", "user-2"))
+	assert.Equal(t, "control", s.Variant("ranking", "user-2", ""))
+}
+
+func TestWatch(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "flags.toml")
+	err := os.WriteFile(p, []byte("[flags.beta]\nenabled = false\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := config.NewWatcher[testConfig](func() *viper.Viper {
+		return config.ViperWithDefaults("flagwatch")
+	}, config.WithFile(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := flags.Watch(w, func(c *testConfig) flags.Config {
+		return c.Flags
+	})
+	assert.False(t, s.Enabled("beta", "u"))
+	// ensure the modification is seen as a change
+	time.Sleep(time.Millisecond * 10)
+	err = os.WriteFile(p, []byte("[flags.beta]\nenabled = true\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, s.Enabled("beta", "u"))
+}
+
+func TestSet_Override(t *testing.T) {
+	s := flags.New(flags.Config{"beta": {}})
+	restore := s.Override("beta", flags.Flag{Enabled: true})
+	assert.True(t, s.Enabled("beta", "u"))
+	// overrides survive updates
+	s.Update(flags.Config{"beta": {}})
+	assert.True(t, s.Enabled("beta", "u"))
+	assert.Equal(t, []flags.State{
+		{Name: "beta", Flag: flags.Flag{Enabled: true}, Overridden: true},
+	}, s.State())
+	restore()
+	assert.False(t, s.Enabled("beta", "u"))
+}
+
+func TestSet_Handler(t *testing.T) {
+	s := flags.New(flags.Config{"beta": {Enabled: true, Variant: "x"}})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/flags?user=u", nil)
+	s.Handler().ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{
+		"name": "beta",
+		"flag": {"enabled": true, "variant": "x"},
+		"enabled": true,
+		"variant": "x"
+	}]`, w.Body.String())
+}
@@ -0,0 +1,19 @@
+// Package flagstest provides helpers for testing code which evaluates
+// feature flags with the flags package.
+package flagstest
+
+import (
+	"testing"
+
+	"go.soon.build/kit/config/flags"
+)
+
+// Override replaces the named flag of s for the duration of a test
+//
+// Example:
+//
+//	flagstest.Override(t, fs, "new_checkout", flags.Flag{Enabled: true})
+func Override(t testing.TB, s *flags.Set, name string, f flags.Flag) {
+	t.Helper()
+	t.Cleanup(s.Override(name, f))
+}
@@ -0,0 +1,18 @@
+package flagstest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config/flags"
+	"go.soon.build/kit/config/flags/flagstest"
+)
+
+func TestOverride(t *testing.T) {
+	s := flags.New(flags.Config{"beta": {}})
+	t.Run("override", func(t *testing.T) {
+		flagstest.Override(t, s, "beta", flags.Flag{Enabled: true})
+		assert.True(t, s.Enabled("beta", "u"))
+	})
+	assert.False(t, s.Enabled("beta", "u"))
+}
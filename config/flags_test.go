@@ -0,0 +1,77 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	type Log struct {
+		Level  string `desc:"log level"`
+		Name   string
+		Custom string `mapstructure:"customTag"`
+		Skip   string `flag:"-"`
+	}
+	type Server struct {
+		Port    int           `default:"5000"`
+		Ratio   float32       `default:"0.5"`
+		Timeout time.Duration `default:"5s"`
+		Hosts   []string
+		Ports   []int
+		Debug   bool
+		Labels  map[string]string
+	}
+	type Config struct {
+		Log    Log
+		Server Server
+	}
+
+	c := Config{
+		Log: Log{Name: "name"},
+	}
+	fs := pflag.NewFlagSet("flags", pflag.ContinueOnError)
+	fs.String("log.name", "flag", "")
+	opts := config.RegisterFlags(fs, &c)
+	assert.Len(t, opts, 9)
+	assert.Nil(t, fs.Lookup("log.skip"))
+	assert.Nil(t, fs.Lookup("server.labels"))
+	assert.Equal(t, "log level", fs.Lookup("log.level").Usage)
+	assert.Equal(t, "5000", fs.Lookup("server.port").DefValue)
+	assert.Equal(t, "duration", fs.Lookup("server.timeout").Value.Type())
+
+	err := fs.Parse([]string{
+		"--log.level=debug",
+		"--log.customTag=custom",
+		"--server.timeout=3s",
+		"--server.hosts=a,b",
+		"--server.ports=1,2",
+		"--server.debug",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := config.ViperWithDefaults("flags")
+	err = config.ReadInConfig(v, &c, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Config{
+		Log: Log{
+			Level:  "debug",
+			Name:   "flag", // existing flag default
+			Custom: "custom",
+		},
+		Server: Server{
+			Port:    5000,
+			Ratio:   0.5,
+			Timeout: time.Second * 3,
+			Hosts:   []string{"a", "b"},
+			Ports:   []int{1, 2},
+			Debug:   true,
+		},
+	}, c)
+}
@@ -1,9 +1,29 @@
 module go.soon.build/kit/config
 
-go 1.16
+go 1.21
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/mitchellh/mapstructure v1.1.2
+	github.com/rs/zerolog v1.30.0
+	github.com/spf13/afero v1.1.2
+	github.com/spf13/cast v1.3.0
 	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.4.0
 	github.com/stretchr/testify v1.7.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/magiconair/properties v1.8.0 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/pelletier/go-toml v1.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/spf13/jwalterweatherman v1.0.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.3.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+var (
+	hooksMu sync.RWMutex // protects hooks
+	hooks   = map[reflect.Type]func(s string) (interface{}, error){}
+)
+
+func init() {
+	RegisterDecodeHook(time.ParseDuration)
+	RegisterDecodeHook(parseIP)
+	RegisterDecodeHook(url.Parse)
+	RegisterDecodeHook(func(s string) (url.URL, error) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	})
+}
+
+// RegisterDecodeHook registers a func which parses string values, from env
+// vars, flags or config files, into fields of type T when a configuration is
+// unmarshalled. Hooks are registered for time.Duration, net.IP, url.URL and
+// *url.URL, types implementing encoding.TextUnmarshaler such as time.Time or
+// zerolog.Level are decoded without a hook. Fields of types with a hook are
+// bound to a single env var rather than walked as nested structs. The
+// returned func removes the hook, restoring any hook it replaced.
+//
+// Example:
+//
+//	config.RegisterDecodeHook(func(s string) (*regexp.Regexp, error) {
+//		return regexp.Compile(s)
+//	})
+func RegisterDecodeHook[T any](fn func(s string) (T, error)) (unregister func()) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	prev, ok := hooks[t]
+	hooks[t] = func(s string) (interface{}, error) {
+		return fn(s)
+	}
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		if ok {
+			hooks[t] = prev
+			return
+		}
+		delete(hooks, t)
+	}
+}
+
+func parseIP(s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	return ip, nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// decodeString decodes s into a value of type t with a registered hook or
+// encoding.TextUnmarshaler, returning false if t has neither
+func decodeString(t reflect.Type, s string) (interface{}, bool, error) {
+	hooksMu.RLock()
+	fn, ok := hooks[t]
+	hooksMu.RUnlock()
+	if ok {
+		val, err := fn(s)
+		return val, true, err
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		val := reflect.New(t)
+		err := val.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		return val.Elem().Interface(), true, err
+	}
+	return nil, false, nil
+}
+
+// isLeaf returns true for types decoded from a single string value, or
+// pointers to them
+func isLeaf(t reflect.Type) bool {
+	decodable := func(t reflect.Type) bool {
+		hooksMu.RLock()
+		_, ok := hooks[t]
+		hooksMu.RUnlock()
+		return ok || reflect.PointerTo(t).Implements(textUnmarshalerType)
+	}
+	return decodable(t) || (t.Kind() == reflect.Ptr && decodable(t.Elem()))
+}
+
+// decodeHook is a mapstructure.DecodeHookFuncType applying the registered
+// decode hooks to string values
+func decodeHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if f.Kind() != reflect.String {
+		return data, nil
+	}
+	val, ok, err := decodeString(t, reflect.ValueOf(data).String())
+	if !ok {
+		return data, nil
+	}
+	return val, err
+}
+
+// unmarshal decodes the settings held by v into c, binding indexed env vars,
+// decrypting values, expanding references and applying the registered
+// decode hooks. Settings are decoded as by viper.Unmarshal.
+func (s *state) unmarshal(v *viper.Viper, c interface{}) error {
+	settings := v.AllSettings()
+	err := s.bindIndexedEnvs(v, c, settings)
+	if err != nil {
+		return err
+	}
+	// values are decrypted first so references to encrypted keys expand to
+	// the decrypted value
+	s.secretKeys, err = decryptSettings(settings, s.key)
+	if err != nil {
+		return err
+	}
+	// only values read from config files and sources are expanded, env
+	// vars and flags are taken as they are
+	err = interpolate(settings, s.lookupEnv, func(key string) bool {
+		src, _ := s.source(v, key, false)
+		return src == SourceFile || src == SourceRemote
+	}, secretFieldKeys(c), s.secretKeys)
+	if err != nil {
+		return err
+	}
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           c,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.DecodeHookFuncType(decodeHook),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return d.Decode(settings)
+}
+
+// isStructSlice returns true for slices of structs, or pointers to structs,
+// which are not decoded from a single string value
+func isStructSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || isLeaf(t) {
+		return false
+	}
+	e := t.Elem()
+	if e.Kind() == reflect.Ptr {
+		e = e.Elem()
+	}
+	return e.Kind() == reflect.Struct && !isLeaf(e)
+}
+
+// elemKeys returns the field keys of a struct element type, relative to the
+// element, or nil for other types
+func elemKeys(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr && !isLeaf(t) {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isLeaf(t) {
+		return nil
+	}
+	var keys []string
+	// walkValue errors are only returned by fn
+	_ = walkValue(reflect.New(t).Elem(), func(f field) error {
+		keys = append(keys, f.key)
+		return nil
+	})
+	return keys
+}
+
+// environ returns the sorted names of the set env vars starting with prefix
+func environ(prefix string) []string {
+	var names []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitEnv splits the remainder of an env var name after a map or slice
+// prefix into the entry, e.g. a map key or slice index, and the element
+// field key it ends with. The longest matching field key wins so entries may
+// contain underscores. Without field keys the whole remainder is the entry.
+func splitEnv(rest string, keys []string) (string, string, bool) {
+	if len(keys) == 0 {
+		return rest, "", true
+	}
+	var entry, key string
+	for _, k := range keys {
+		suffix := "_" + strings.ToUpper(strings.ReplaceAll(k, ".", "_"))
+		if strings.HasSuffix(rest, suffix) && len(rest) > len(suffix) && len(k) > len(key) {
+			entry, key = strings.TrimSuffix(rest, suffix), k
+		}
+	}
+	return entry, key, key != ""
+}
+
+// bindMapEnvs binds env vars prefixed with the env var name of a map field to
+// its entries e.g. NAME_LABELS_TEAM to `labels.team`. For maps of structs the
+// entry is followed by the element field e.g. NAME_DBS_PRIMARY_HOST binds
+// `dbs.primary.host`.
+func bindMapEnvs(v *viper.Viper, f field) error {
+	prefix := envName(v, f.key) + "_"
+	for name, key := range mapEnvKeys(f, prefix, environ(prefix)) {
+		err := v.BindEnv(key, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapEnvKeys returns the keys of the entries of a map field set by env vars
+// names, by env var name
+func mapEnvKeys(f field, prefix string, names []string) map[string]string {
+	keys := elemKeys(f.value.Type().Elem())
+	entries := map[string]string{}
+	for _, name := range names {
+		entry, key, ok := splitEnv(strings.TrimPrefix(name, prefix), keys)
+		if !ok {
+			continue
+		}
+		k := f.key + "." + strings.ToLower(entry)
+		if key != "" {
+			k += "." + key
+		}
+		entries[name] = k
+	}
+	return entries
+}
+
+// bindIndexedEnvs sets slice of struct fields in settings from indexed env
+// vars e.g. NAME_BROKERS_0_HOST sets `host` of the first element of
+// `brokers`. Elements read from config files are kept, env vars override
+// their fields and extend the slice as needed.
+func (s *state) bindIndexedEnvs(v *viper.Viper, c interface{}, settings map[string]interface{}) error {
+	return walkFields(c, func(f field) error {
+		if !isStructSlice(f.value.Type()) {
+			return nil
+		}
+		prefix := envName(v, f.key) + "_"
+		keys := elemKeys(f.value.Type().Elem())
+		var items []map[string]interface{}
+		set := false
+		for _, name := range s.environ(prefix) {
+			entry, key, ok := splitEnv(strings.TrimPrefix(name, prefix), keys)
+			if !ok {
+				continue
+			}
+			i, err := strconv.Atoi(entry)
+			if err != nil || i < 0 {
+				continue
+			}
+			if !set {
+				items, set = elements(lookupPath(settings, f.key)), true
+			}
+			for len(items) <= i {
+				items = append(items, map[string]interface{}{})
+			}
+			val, _ := s.lookupEnv(name)
+			setPath(items[i], strings.Split(key, "."), val)
+		}
+		if set {
+			setPath(settings, strings.Split(strings.ToLower(f.key), "."), items)
+		}
+		return nil
+	})
+}
+
+// elements copies a list of tables read from a config file into a slice of
+// maps with lowercased keys
+func elements(val interface{}) []map[string]interface{} {
+	var items []map[string]interface{}
+	for _, e := range cast.ToSlice(val) {
+		items = append(items, copyMap(e))
+	}
+	return items
+}
+
+// lookupPath returns the value of a dotted key in nested maps
+func lookupPath(m map[string]interface{}, key string) interface{} {
+	var val interface{} = m
+	for _, p := range strings.Split(strings.ToLower(key), ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		val = m[p]
+	}
+	return val
+}
+
+// setPath sets a value in nested maps by its key path, nested maps are
+// copied rather than modified in place
+func setPath(m map[string]interface{}, path []string, val interface{}) {
+	if len(path) == 1 {
+		m[path[0]] = val
+		return
+	}
+	sub := copyMap(m[path[0]])
+	m[path[0]] = sub
+	setPath(sub, path[1:], val)
+}
+
+func copyMap(val interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range cast.ToStringMap(val) {
+		m[strings.ToLower(k)] = v
+	}
+	return m
+}
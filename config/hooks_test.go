@@ -0,0 +1,177 @@
+package config_test
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+type level int8
+
+func (l *level) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		*l = -1
+	}
+	return nil
+}
+
+func TestReadInConfig_FieldShapes(t *testing.T) {
+	type TLS struct {
+		Cert string
+	}
+	type Broker struct {
+		Host string
+		TLS  TLS `mapstructure:"tls"`
+	}
+	type DB struct {
+		Host     string
+		PoolSize int `mapstructure:"pool_size"`
+	}
+	type Common struct {
+		Name string
+	}
+	type Config struct {
+		Common  `mapstructure:",squash"`
+		Log     *struct{ Level string }
+		Ignored string `mapstructure:"-"`
+		Labels  map[string]string
+		DBs     map[string]DB `mapstructure:"dbs"`
+		Brokers []Broker
+	}
+
+	p := filepath.Join(t.TempDir(), "shapes.toml")
+	writeFile(t, p, `
+[[brokers]]
+host = "a:9092"
+
+[[brokers]]
+host = "b:9092"
+
+[dbs.primary]
+host = "db:5432"
+`)
+	for k, v := range map[string]string{
+		"SHAPES_NAME":                 "name",
+		"SHAPES_LOG_LEVEL":            "debug",
+		"SHAPES_IGNORED":              "ignored",
+		"SHAPES_LABELS_TEAM":          "platform",
+		"SHAPES_DBS_PRIMARY_HOST":     "primary:5432",
+		"SHAPES_DBS_READ_1_POOL_SIZE": "4",
+		"SHAPES_BROKERS_1_TLS_CERT":   "cert.pem",
+		"SHAPES_BROKERS_2_HOST":       "c:9092",
+	} {
+		t.Setenv(k, v)
+	}
+	var c Config
+	v := config.ViperWithDefaults("shapes")
+	err := config.ReadInConfig(v, &c, config.WithFile(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "name", c.Name)
+	if assert.NotNil(t, c.Log) {
+		assert.Equal(t, "debug", c.Log.Level)
+	}
+	assert.Equal(t, "", c.Ignored)
+	assert.Equal(t, map[string]string{"team": "platform"}, c.Labels)
+	assert.Equal(t, map[string]DB{
+		"primary": {Host: "primary:5432"},
+		"read_1":  {PoolSize: 4},
+	}, c.DBs)
+	assert.Equal(t, []Broker{
+		{Host: "a:9092"},
+		{Host: "b:9092", TLS: TLS{Cert: "cert.pem"}},
+		{Host: "c:9092"},
+	}, c.Brokers)
+}
+
+func TestReadInConfig_DecodeHooks(t *testing.T) {
+	t.Cleanup(config.RegisterDecodeHook(func(s string) (*regexp.Regexp, error) {
+		return regexp.Compile(s)
+	}))
+	type Config struct {
+		Timeout  time.Duration
+		IP       net.IP `mapstructure:"ip"`
+		Endpoint *url.URL
+		Origin   url.URL
+		Since    time.Time
+		Level    level
+		Match    *regexp.Regexp
+		Hosts    []string
+		Fallback net.IP `mapstructure:"fallback" default:"127.0.0.1"`
+	}
+	for k, v := range map[string]string{
+		"HOOKS_TIMEOUT":  "5s",
+		"HOOKS_IP":       "10.0.0.1",
+		"HOOKS_ENDPOINT": "https://example.com/a",
+		"HOOKS_ORIGIN":   "https://example.com",
+		"HOOKS_SINCE":    "2020-01-02T03:04:05Z",
+		"HOOKS_LEVEL":    "info",
+		"HOOKS_MATCH":    "^a+$",
+		"HOOKS_HOSTS":    "a,b",
+	} {
+		t.Setenv(k, v)
+	}
+	var c Config
+	err := config.ReadInConfig(config.ViperWithDefaults("hooks"), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Second*5, c.Timeout)
+	assert.Equal(t, "10.0.0.1", c.IP.String())
+	assert.Equal(t, "https://example.com/a", c.Endpoint.String())
+	assert.Equal(t, "example.com", c.Origin.Host)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), c.Since)
+	assert.Equal(t, level(1), c.Level)
+	assert.True(t, c.Match.MatchString("aaa"))
+	assert.Equal(t, []string{"a", "b"}, c.Hosts)
+	assert.Equal(t, "127.0.0.1", c.Fallback.String())
+}
+
+func TestReadInConfig_DecodeHookError(t *testing.T) {
+	type Config struct {
+		IP net.IP `mapstructure:"ip"`
+	}
+	t.Setenv("HOOKERR_IP", "not-an-ip")
+	var c Config
+	err := config.ReadInConfig(config.ViperWithDefaults("hookerr"), &c)
+	if err == nil || !strings.Contains(err.Error(), "not-an-ip") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRegisterDecodeHook_Unregister(t *testing.T) {
+	type Config struct {
+		Timeout time.Duration
+	}
+	t.Setenv("UNREGISTER_TIMEOUT", "1s")
+	unregister := config.RegisterDecodeHook(func(s string) (time.Duration, error) {
+		return time.Minute, nil
+	})
+	var c Config
+	err := config.ReadInConfig(config.ViperWithDefaults("unregister"), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Minute, c.Timeout)
+
+	// the replaced hook is restored
+	unregister()
+	err = config.ReadInConfig(config.ViperWithDefaults("unregister"), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Second, c.Timeout)
+}
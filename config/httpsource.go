@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultHTTPTimeout bounds requests made by an HTTPSource with the default
+// client
+const defaultHTTPTimeout = time.Second * 10
+
+// An HTTPSource is a RemoteSource fetching TOML or JSON settings from a URL.
+// Requests send the ETag of the last response in an If-None-Match header so
+// unchanged settings are not downloaded again. When the URL cannot be
+// fetched the last response is used, falling back to a cache file on disk
+// if one is configured.
+type HTTPSource struct {
+	url    string
+	client *http.Client
+	cache  string // cache file path
+	format string // toml or json, detected when empty
+
+	mu    sync.Mutex // protects etag, body and fresh
+	etag  string
+	body  []byte
+	typ   string
+	fresh bool // body was fetched by Poll and not loaded yet
+}
+
+// An HTTPSourceOption configures an HTTPSource
+type HTTPSourceOption func(s *HTTPSource)
+
+// WithHTTPClient sets the client used to fetch settings
+func WithHTTPClient(c *http.Client) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.client = c
+	}
+}
+
+// WithCacheFile sets a file the last fetched settings are written to, it
+// is read when the URL cannot be fetched e.g. on startup while the config
+// service is down
+func WithCacheFile(p string) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.cache = p
+	}
+}
+
+// WithFormat sets the format of the fetched settings, `toml` or `json`. By
+// default the format is detected from the response Content-Type or the URL
+// extension, defaulting to TOML.
+func WithFormat(format string) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.format = format
+	}
+}
+
+// NewHTTPSource constructs an HTTPSource fetching settings from u
+func NewHTTPSource(u string, opts ...HTTPSourceOption) *HTTPSource {
+	s := &HTTPSource{
+		url:    u,
+		client: &http.Client{Timeout: defaultHTTPTimeout},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Name returns the source URL
+func (s *HTTPSource) Name() string {
+	return s.url
+}
+
+// Load fetches the settings, falling back to the last fetched or cached
+// settings on error. Settings changed by Poll are used without fetching them
+// again.
+func (s *HTTPSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	s.mu.Lock()
+	fresh := s.fresh
+	s.fresh = false
+	s.mu.Unlock()
+	var err error
+	if !fresh {
+		_, err = s.fetch(ctx)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && s.body == nil {
+		if s.cache == "" {
+			return nil, err
+		}
+		b, cerr := os.ReadFile(s.cache)
+		if cerr != nil {
+			return nil, fmt.Errorf("%v, no cached copy: %v", err, cerr)
+		}
+		ext := filepath.Ext(s.cache)
+		if configTypes[ext] == "" {
+			ext = urlExt(s.url)
+		}
+		s.body, s.typ = b, s.detect("", ext)
+	}
+	fv := viper.New()
+	fv.SetConfigType(s.typ)
+	err = fv.ReadConfig(bytes.NewReader(s.body))
+	if err != nil {
+		return nil, err
+	}
+	return fv.AllSettings(), nil
+}
+
+// Poll fetches the settings every interval calling onChange when they have
+// changed, e.g. with Watcher.Reload. Fetch errors are ignored, the next
+// interval retries. Poll blocks until ctx is done.
+//
+// Example:
+//
+//	src := config.NewHTTPSource(u)
+//	w, err := config.NewWatcher[Config](newViper, config.WithSource(src))
+//	...
+//	go src.Poll(ctx, time.Minute, func() {
+//		_ = w.Reload() // errors are reported to OnError funcs
+//	})
+func (s *HTTPSource) Poll(ctx context.Context, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.fetch(ctx)
+			if err == nil && changed {
+				s.mu.Lock()
+				s.fresh = true
+				s.mu.Unlock()
+				onChange()
+			}
+		}
+	}
+}
+
+// fetch requests the settings, returning true if they have changed since
+// the last fetch
+func (s *HTTPSource) fetch(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	if s.etag != "" && s.body != nil {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	s.mu.Unlock()
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer rsp.Body.Close()
+	switch rsp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("unexpected response status %s", rsp.Status)
+	}
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := !bytes.Equal(b, s.body)
+	s.etag = rsp.Header.Get("ETag")
+	s.body = b
+	s.typ = s.detect(rsp.Header.Get("Content-Type"), urlExt(s.url))
+	if s.cache != "" && changed {
+		err = writeAtomic(s.cache, b)
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// detect returns the format of the settings from the configured format,
+// a content type or a file extension
+func (s *HTTPSource) detect(contentType, ext string) string {
+	if s.format != "" {
+		return s.format
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mt, "json"):
+		return "json"
+	case strings.HasSuffix(mt, "toml"):
+		return "toml"
+	case ext == ".json":
+		return "json"
+	}
+	return "toml"
+}
+
+func urlExt(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(pu.Path)
+}
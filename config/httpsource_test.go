@@ -0,0 +1,180 @@
+package config_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+// remoteConfig serves settings with an ETag, counting full responses
+type remoteConfig struct {
+	mu      sync.Mutex
+	body    string
+	etag    string
+	fetched int32
+}
+
+func (rc *remoteConfig) set(body, etag string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.body, rc.etag = body, etag
+}
+
+func (rc *remoteConfig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if r.Header.Get("If-None-Match") == rc.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	atomic.AddInt32(&rc.fetched, 1)
+	w.Header().Set("ETag", rc.etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(rc.body))
+}
+
+type remoteTestConfig struct {
+	Name    string
+	Port    int
+	Tenants []string
+	Log     struct {
+		Level string
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	rc := &remoteConfig{}
+	rc.set(`{"port": 8000, "tenants": ["a", "b"], "log": {"level": "debug"}}`, `"v1"`)
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, "remote.toml")
+	writeFile(t, p, `
+name = "file"
+port = 5000
+
+[log]
+level = "info"
+`)
+	t.Setenv("REMOTE_LOG_LEVEL", "warn")
+	cache := filepath.Join(dir, "cache.json")
+	src := config.NewHTTPSource(srv.URL, config.WithCacheFile(cache))
+
+	var c remoteTestConfig
+	var r config.Report
+	err := config.ReadInConfig(config.ViperWithDefaults("remote"), &c, config.WithFile(p), config.WithSource(src), config.WithReport(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "file", c.Name)
+	assert.Equal(t, 8000, c.Port, "remote settings override files")
+	assert.Equal(t, []string{"a", "b"}, c.Tenants)
+	assert.Equal(t, "warn", c.Log.Level, "env vars override remote settings")
+	for _, s := range r.Explain(&c) {
+		if s.Key == "port" {
+			assert.Equal(t, config.SourceRemote, s.Source)
+			assert.Equal(t, srv.URL, s.Origin)
+		}
+	}
+	assert.Equal(t, []string{p}, r.Layers(), "sources are not config files")
+
+	// unchanged settings are not fetched again
+	err = config.ReadInConfig(config.ViperWithDefaults("remote"), &c, config.WithFile(p), config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rc.fetched))
+
+	// a new source falls back to the cache file when the server is down
+	srv.Close()
+	c = remoteTestConfig{}
+	err = config.ReadInConfig(config.ViperWithDefaults("remote"), &c,
+		config.WithSource(config.NewHTTPSource(srv.URL, config.WithCacheFile(cache))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 8000, c.Port)
+
+	// without a cache the error is returned
+	err = config.ReadInConfig(config.ViperWithDefaults("remote"), &c,
+		config.WithSource(config.NewHTTPSource(srv.URL)))
+	assert.Error(t, err)
+}
+
+func TestHTTPSource_ReadInAllDirConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("port = 9000\n"))
+	}))
+	defer srv.Close()
+
+	var c remoteTestConfig
+	v, _ := config.ViperWithDir("remote")
+	err := config.ReadInAllDirConfig(v, "testdata/profiles", &c, config.WithSource(config.NewHTTPSource(srv.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 9000, c.Port)
+	assert.Equal(t, "debug", c.Log.Level, "from staging.yaml")
+}
+
+func TestHTTPSource_Poll(t *testing.T) {
+	rc := &remoteConfig{}
+	rc.set(`{"port": 8000}`, `"v1"`)
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	src := config.NewHTTPSource(srv.URL)
+	w, err := config.NewWatcher[remoteTestConfig](func() *viper.Viper {
+		return config.ViperWithDefaults("poll")
+	}, config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan *remoteTestConfig, 1)
+	w.OnChange(func(_, new *remoteTestConfig) {
+		changed <- new
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go src.Poll(ctx, time.Millisecond*10, func() {
+		_ = w.Reload()
+	})
+
+	rc.set(`{"port": 9000}`, `"v2"`)
+	select {
+	case c := <-changed:
+		assert.Equal(t, 9000, c.Port)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for change")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&rc.fetched), "reload uses the settings fetched by Poll")
+}
+
+func TestHTTPSource_Timeout(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	var c remoteTestConfig
+	err := config.ReadInConfig(config.ViperWithDefaults("remote"), &c,
+		config.WithSource(config.NewHTTPSource(srv.URL)), config.WithSourceTimeout(time.Millisecond*50))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A ReferenceError reports a reference in a configuration value which could
+// not be expanded
+type ReferenceError struct {
+	Key string // key whose value holds the reference e.g. `db.url`
+	Ref string // reference e.g. `${db.host}`
+	Msg string
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s: %s %s", e.Key, e.Ref, e.Msg)
+}
+
+// interpolate expands references in the string values of settings, see
+// ReadInConfig. Env var references are named by upper case letters, digits
+// and underscores, other references are configuration keys which may hold
+// references themselves. A value consisting of a single key reference takes
+// the referenced value as is, e.g. a number. Only the values of keys for
+// which expands returns true are expanded, others are literal but may still
+// be referenced. Secret references held by the keys in tagged, the fields
+// tagged `secret:"true"`, are resolved when referenced. Keys referencing the
+// keys in tagged or secrets are added to secrets, their values hold the
+// secret once expanded.
+func interpolate(settings map[string]interface{}, lookupEnv func(string) (string, bool), expands func(key string) bool, tagged, secrets map[string]bool) error {
+	in := &interpolator{
+		settings:  settings,
+		lookupEnv: lookupEnv,
+		expands:   expands,
+		tagged:    tagged,
+		secrets:   secrets,
+		resolving: map[string]bool{},
+		resolved:  map[string]bool{},
+	}
+	var errs []error
+	seen := map[string]bool{}
+	for _, key := range leafKeys(settings, "") {
+		_, err := in.resolve(key, nil)
+		// errors of referenced keys are reported by each referencing key
+		if err != nil && !seen[err.Error()] {
+			seen[err.Error()] = true
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type interpolator struct {
+	settings  map[string]interface{}
+	lookupEnv func(string) (string, bool)
+	expands   func(key string) bool
+	tagged    map[string]bool // keys of fields tagged as secret
+	secrets   map[string]bool // keys holding secret values
+	resolving map[string]bool // keys being resolved, to detect cycles
+	resolved  map[string]bool // keys with expanded values
+}
+
+// resolve expands the references in the value of key, replacing it in the
+// settings. path holds the keys referencing key.
+func (in *interpolator) resolve(key string, path []string) (interface{}, error) {
+	val := lookupPath(in.settings, key)
+	if in.resolved[key] || !hasRef(val) || !in.expands(key) {
+		return val, nil
+	}
+	if in.resolving[key] {
+		return nil, &ReferenceError{
+			Key: path[len(path)-1],
+			Ref: "${" + key + "}",
+			Msg: fmt.Sprintf("forms a cycle %s", strings.Join(append(path, key), " -> ")),
+		}
+	}
+	in.resolving[key] = true
+	defer delete(in.resolving, key)
+	var exp interface{}
+	switch v := val.(type) {
+	case string:
+		var err error
+		exp, err = in.expand(key, v, append(path, key))
+		if err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+			if s, ok := item.(string); ok && strings.Contains(s, "${") {
+				exp, err := in.expand(key, s, append(path, key))
+				if err != nil {
+					return nil, err
+				}
+				items[i] = exp
+			}
+		}
+		exp = items
+	}
+	setPath(in.settings, strings.Split(key, "."), exp)
+	in.resolved[key] = true
+	return exp, nil
+}
+
+// hasRef returns true for string values, or lists of them, which may hold
+// references
+func hasRef(val interface{}) bool {
+	switch v := val.(type) {
+	case string:
+		return strings.Contains(v, "${")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.Contains(s, "${") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expand expands the references in s, a value of key
+func (in *interpolator) expand(key, s string, path []string) (interface{}, error) {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 2
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, &ReferenceError{Key: key, Ref: s[i:], Msg: "is not closed"}
+		}
+		ref := s[i : i+end+1]
+		name, def, hasDef := strings.Cut(ref[2:len(ref)-1], ":-")
+		val, ok, err := in.lookup(name, path)
+		if err != nil {
+			return nil, err
+		}
+		if (!ok || val == "") && hasDef {
+			val, ok = def, true
+		}
+		if !ok {
+			return nil, &ReferenceError{Key: key, Ref: ref, Msg: "is not set"}
+		}
+		if ref == s && !isEnvRef(name) {
+			// a single key reference keeps the referenced type
+			return val, nil
+		}
+		b.WriteString(fmt.Sprint(val))
+		i += end
+	}
+	return b.String(), nil
+}
+
+// lookup returns the value of an env var or configuration key reference
+func (in *interpolator) lookup(name string, path []string) (interface{}, bool, error) {
+	if isEnvRef(name) {
+		val, ok := in.lookupEnv(name)
+		return val, ok, nil
+	}
+	key := strings.ToLower(name)
+	val := lookupPath(in.settings, key)
+	switch val.(type) {
+	case nil:
+		return nil, false, nil
+	case map[string]interface{}:
+		return nil, false, &ReferenceError{Key: path[len(path)-1], Ref: "${" + name + "}", Msg: "is not a value"}
+	}
+	val, err := in.resolve(key, path)
+	if err != nil {
+		return nil, false, err
+	}
+	if ref, ok := val.(string); ok && in.tagged[key] {
+		// expand to the secret rather than its reference
+		val, err = resolveSecret(key, ref)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if in.secrets[key] || in.tagged[key] {
+		in.secrets[path[len(path)-1]] = true
+	}
+	return val, val != nil, nil
+}
+
+// isEnvRef returns true for references to env vars
+func isEnvRef(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// leafKeys returns the sorted dotted keys of the leaf values of nested maps
+func leafKeys(m map[string]interface{}, prefix string) []string {
+	var keys []string
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			keys = append(keys, leafKeys(sub, prefix+k+".")...)
+			continue
+		}
+		keys = append(keys, prefix+k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestReadInConfig_Interpolation(t *testing.T) {
+	type Config struct {
+		Project string
+		Host    string
+		Port    int
+		Server  struct {
+			Port int
+			URL  string `mapstructure:"url"`
+		}
+		Topic   string
+		Region  string
+		Literal string
+		Hosts   []string
+	}
+	p := filepath.Join(t.TempDir(), "interp.toml")
+	writeFile(t, p, `
+project = "${INTERP_PROJECT_ID}"
+host = "api.${project}.internal"
+port = "${server.port}"
+topic = "projects/${project}/topics/events"
+region = "${INTERP_REGION:-europe-west2}"
+literal = "$${host}"
+hosts = ["${host}", "other"]
+
+[server]
+port = 5000
+url = "https://${host}:${server.port}"
+`)
+	t.Setenv("INTERP_PROJECT_ID", "soon")
+	var c Config
+	err := config.ReadInConfig(config.ViperWithDefaults("interp"), &c, config.WithFile(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "soon", c.Project)
+	assert.Equal(t, "api.soon.internal", c.Host)
+	assert.Equal(t, 5000, c.Port)
+	assert.Equal(t, "https://api.soon.internal:5000", c.Server.URL)
+	assert.Equal(t, "projects/soon/topics/events", c.Topic)
+	assert.Equal(t, "europe-west2", c.Region)
+	assert.Equal(t, "${host}", c.Literal)
+	assert.Equal(t, []string{"api.soon.internal", "other"}, c.Hosts)
+
+	// env vars are not expanded but may be referenced
+	t.Setenv("INTERP_HOST", "p${word}")
+	t.Setenv("INTERP_LITERAL", "pa${ss")
+	c = Config{}
+	err = config.ReadInConfig(config.ViperWithDefaults("interp"), &c, config.WithFile(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "p${word}", c.Host)
+	assert.Equal(t, "pa${ss", c.Literal)
+	assert.Equal(t, "https://p${word}:5000", c.Server.URL)
+}
+
+func TestReadInConfig_InterpolationErrors(t *testing.T) {
+	type Config struct {
+		A   string
+		B   string
+		URL string `mapstructure:"url"`
+		Log struct {
+			Level string
+		}
+		Sink string
+		List []string
+	}
+	p := filepath.Join(t.TempDir(), "interperr.toml")
+	writeFile(t, p, `
+a = "${b}"
+b = "x${a}"
+list = ["x${list}"]
+url = "http://${INTERPERR_UNSET}/${missing.key}"
+sink = "${log}"
+
+[log]
+level = "info"
+`)
+	var c Config
+	err := config.ReadInConfig(config.ViperWithDefaults("interperr"), &c, config.WithFile(p))
+	var rerr *config.ReferenceError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected ReferenceError, got %v", err)
+	}
+	assert.EqualError(t, err, "b: ${a} forms a cycle a -> b -> a\n"+
+		"a: ${b} forms a cycle b -> a -> b\n"+
+		"list: ${list} forms a cycle list -> list\n"+
+		"sink: ${log} is not a value\n"+
+		"url: ${INTERPERR_UNSET} is not set")
+}
+
+func TestReadInConfig_InterpolationSecret(t *testing.T) {
+	type Config struct {
+		Pass string `secret:"true"`
+		URL  string `mapstructure:"url"`
+	}
+	dir := t.TempDir()
+	pw := filepath.Join(dir, "pw")
+	writeFile(t, pw, "s3cret\n")
+	p := filepath.Join(dir, "interpsecret.toml")
+	writeFile(t, p, `
+pass = "file://`+pw+`"
+url = "postgres://u:${pass}@h"
+`)
+	var c Config
+	var r config.Report
+	err := config.ReadInConfig(config.ViperWithDefaults("interpsecret"), &c, config.WithFile(p), config.WithReport(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// references to secret fields expand to the resolved secret
+	assert.Equal(t, Config{Pass: "s3cret", URL: "postgres://u:s3cret@h"}, c)
+	for _, s := range r.Explain(&c) {
+		assert.Equal(t, "[REDACTED]", s.Value, s.Key)
+	}
+
+	writeFile(t, p, `
+pass = "file://`+filepath.Join(dir, "missing")+`"
+url = "postgres://u:${pass}@h"
+`)
+	err = config.ReadInConfig(config.ViperWithDefaults("interpsecret"), &Config{}, config.WithFile(p))
+	var serr *config.SecretError
+	assert.True(t, errors.As(err, &serr), "unresolved secret reference")
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Layer names with a fixed position in the ReadInAllDirConfig merge order
+const (
+	baseLayer  = "base"
+	localLayer = "local"
+)
+
+// WithProfiles returns an Option declaring the names of profile overlay
+// files for ReadInAllDirConfig, so overlays for inactive profiles are not
+// merged. Overlays for active profiles are always applied.
+//
+// Example:
+//
+//	// merges base.toml and staging.toml when NAME_PROFILE=staging,
+//	// production.toml is skipped
+//	config.WithProfiles("staging", "production")
+func WithProfiles(profiles ...string) Option {
+	return withState(func(s *state) {
+		s.profiles = profiles
+	})
+}
+
+// Profiles returns the active profiles set in the `NAME_PROFILE` env var
+func Profiles(v *viper.Viper) []string {
+	return profiles(os.Getenv(envName(v, "profile")))
+}
+
+// profiles splits a comma separated list of profiles
+func profiles(list string) []string {
+	var profiles []string
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// dirLayers returns the config files in directory p in merge order
+func (s *state) dirLayers(v *viper.Viper, p string) ([]string, error) {
+	dir, err := os.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	// the active profiles may be set by a dotenv file
+	list, _ := s.lookupEnv(envName(v, "profile"))
+	active := profiles(list)
+	overlays := map[string]bool{localLayer: true}
+	for _, name := range append(s.profiles, active...) {
+		overlays[name] = true
+	}
+	// files by layer name, os.ReadDir sorts entries by file name
+	byName := map[string][]string{}
+	var names []string
+	for _, d := range dir {
+		ext := filepath.Ext(d.Name())
+		if d.IsDir() || configTypes[ext] == "" {
+			continue
+		}
+		name := strings.TrimSuffix(d.Name(), ext)
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], filepath.Join(p, d.Name()))
+	}
+	sort.Strings(names)
+	files := byName[baseLayer]
+	for _, name := range names {
+		if name != baseLayer && !overlays[name] {
+			files = append(files, byName[name]...)
+		}
+	}
+	for _, name := range active {
+		if name != baseLayer && name != localLayer {
+			files = append(files, byName[name]...)
+		}
+	}
+	return append(files, byName[localLayer]...), nil
+}
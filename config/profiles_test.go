@@ -0,0 +1,96 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestReadInAllDirConfig_Profiles(t *testing.T) {
+	type Sink struct {
+		Console bool
+		Target  string
+	}
+	type Log struct {
+		Level  string
+		Name   string
+		Custom string
+		Sink   Sink
+	}
+	type Config struct {
+		Log Log
+	}
+
+	tests := map[string]struct {
+		profile    string
+		want       Config
+		wantLayers []string
+	}{
+		"no profile": {
+			want: Config{
+				Log: Log{
+					Level:  "info",
+					Name:   "app",
+					Custom: "local",
+					Sink:   Sink{Console: true, Target: "stdout"},
+				},
+			},
+			wantLayers: []string{
+				"testdata/profiles/base.toml",
+				"testdata/profiles/app.toml",
+				"testdata/profiles/local.json",
+			},
+		},
+		"staging": {
+			profile: "staging",
+			want: Config{
+				Log: Log{
+					Level:  "debug",
+					Name:   "app",
+					Custom: "local",
+					Sink:   Sink{Console: true, Target: "stderr"},
+				},
+			},
+			wantLayers: []string{
+				"testdata/profiles/base.toml",
+				"testdata/profiles/app.toml",
+				"testdata/profiles/staging.yaml",
+				"testdata/profiles/local.json",
+			},
+		},
+		"multiple profiles": {
+			profile: "production, staging",
+			want: Config{
+				Log: Log{
+					Level:  "debug",
+					Name:   "app",
+					Custom: "local",
+					Sink:   Sink{Console: true, Target: "stderr"},
+				},
+			},
+			wantLayers: []string{
+				"testdata/profiles/base.toml",
+				"testdata/profiles/app.toml",
+				"testdata/profiles/production.toml",
+				"testdata/profiles/staging.yaml",
+				"testdata/profiles/local.json",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROFILES_PROFILE", tt.profile)
+			c := Config{}
+			v, _ := config.ViperWithDir("profiles")
+			tp := "testdata/profiles"
+			var r config.Report
+			err := config.ReadInAllDirConfig(v, tp, &c, config.WithProfiles("staging", "production"), config.WithReport(&r))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, c)
+			assert.Equal(t, tt.wantLayers, r.Layers())
+		})
+	}
+}
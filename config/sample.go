@@ -0,0 +1,248 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// WriteSample writes a commented sample TOML config file for the struct c.
+// Each key is preceded by its `desc` struct tag and the env var bound to it
+// by viper instance v. Keys are set to the value held by c or their `default`
+// struct tag, keys with neither and secret keys are commented out. Slices
+// and maps of structs are written as commented table templates unless c
+// holds elements.
+//
+// Example:
+//
+//	//go:generate go run ./cmd/sample
+//	err := config.WriteSample(os.Stdout, config.ViperWithDefaults("name"), &Config{})
+func WriteSample(w io.Writer, v *viper.Viper, c interface{}) error {
+	var (
+		tables      []string
+		entries     = map[string][]field{}
+		collections []field
+	)
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		if isStructSlice(f.value.Type()) || (f.value.Kind() == reflect.Map && elemKeys(f.value.Type().Elem()) != nil) {
+			collections = append(collections, f)
+			return nil
+		}
+		table := ""
+		if i := strings.LastIndex(f.key, "."); i >= 0 {
+			table = f.key[:i]
+		}
+		if _, ok := entries[table]; !ok && table != "" {
+			tables = append(tables, table)
+		}
+		entries[table] = append(entries[table], f)
+		return nil
+	})
+	b := &strings.Builder{}
+	for _, f := range entries[""] {
+		writeEntry(b, v, f, f.key)
+	}
+	for _, t := range tables {
+		fmt.Fprintf(b, "[%s]\n\n", t)
+		for _, f := range entries[t] {
+			writeEntry(b, v, f, f.key[len(t)+1:])
+		}
+	}
+	for _, f := range collections {
+		writeCollection(b, v, f)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeEntry writes a commented key value pair
+func writeEntry(b *strings.Builder, v *viper.Viper, f field, name string) {
+	if d := f.tag.Get("desc"); d != "" {
+		fmt.Fprintf(b, "# %s\n", d)
+	}
+	env := envName(v, f.key)
+	if f.value.Kind() == reflect.Map {
+		env += "_<KEY>"
+	}
+	fmt.Fprintf(b, "# env: %s\n", env)
+	if isRequired(f) {
+		b.WriteString("# required\n")
+	}
+	val, ok := sampleValue(f)
+	if !ok {
+		b.WriteString("# ")
+	}
+	fmt.Fprintf(b, "%s = %s\n\n", tomlKey(name), tomlValue(val))
+}
+
+// writeCollection writes the elements of a slice or map of structs as
+// tables, or a commented template table when there are none
+func writeCollection(b *strings.Builder, v *viper.Viper, f field) {
+	header, index := "[["+f.key+"]]", "<N>"
+	if f.value.Kind() == reflect.Map {
+		header, index = "["+f.key+".<name>]", "<NAME>"
+	}
+	if d := f.tag.Get("desc"); d != "" {
+		fmt.Fprintf(b, "# %s\n", d)
+	}
+	keys := elemKeys(f.value.Type().Elem())
+	envs := make([]string, len(keys))
+	for i, k := range keys {
+		envs[i] = envName(v, f.key+"."+index+"."+k)
+	}
+	fmt.Fprintf(b, "# env: %s\n", strings.Join(envs, ", "))
+	if f.value.Len() == 0 {
+		fmt.Fprintf(b, "# %s\n", header)
+		writeElem(b, reflect.New(f.value.Type().Elem()).Elem(), "# ")
+		b.WriteString("\n")
+		return
+	}
+	if f.value.Kind() == reflect.Slice {
+		for i := 0; i < f.value.Len(); i++ {
+			fmt.Fprintf(b, "%s\n", header)
+			writeElem(b, f.value.Index(i), "")
+			b.WriteString("\n")
+		}
+		return
+	}
+	names := make([]string, 0, f.value.Len())
+	for _, k := range f.value.MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "[%s.%s]\n", f.key, tomlKey(name))
+		writeElem(b, f.value.MapIndex(reflect.ValueOf(name)), "")
+		b.WriteString("\n")
+	}
+}
+
+// writeElem writes the fields of a struct element as dotted key value pairs
+// each prefixed with prefix
+func writeElem(b *strings.Builder, elem reflect.Value, prefix string) {
+	if elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			elem = reflect.New(elem.Type().Elem())
+		}
+		elem = elem.Elem()
+	}
+	// walkValue errors are only returned by fn
+	_ = walkValue(elem, func(f field) error {
+		val, ok := sampleValue(f)
+		p := prefix
+		if !ok {
+			p = "# "
+		}
+		fmt.Fprintf(b, "%s%s = %s\n", p, f.key, tomlValue(val))
+		return nil
+	})
+}
+
+// sampleValue returns the value of a field or its default, returning false
+// for secret fields and fields with neither
+func sampleValue(f field) (reflect.Value, bool) {
+	val := f.value
+	if d, ok := f.tag.Lookup("default"); ok && val.IsZero() {
+		dv, err := parseValue(val.Type(), d)
+		if err == nil {
+			val = dv
+		}
+	}
+	if isSecret(f) || val.IsZero() {
+		return reflect.Zero(val.Type()), false
+	}
+	return val, true
+}
+
+// isRequired returns true for fields which must be set, with the required
+// validation rule or min and max rules the zero value fails
+func isRequired(f field) bool {
+	for _, rule := range strings.Split(f.tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return zeroFails(f)
+}
+
+// tomlKey quotes a TOML key unless it is a valid bare key
+func tomlKey(k string) string {
+	for _, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return strconv.Quote(k)
+		}
+	}
+	if k == "" {
+		return `""`
+	}
+	return k
+}
+
+// tomlValue formats a value as a TOML value, types with a decode hook are
+// written as strings
+func tomlValue(val reflect.Value) string {
+	if isLeaf(val.Type()) {
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return `""`
+			}
+			val = val.Elem()
+		}
+		return strconv.Quote(text(val))
+	}
+	switch val.Kind() {
+	case reflect.String:
+		return strconv.Quote(val.String())
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(val.Interface())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, val.Len())
+		for i := range items {
+			items[i] = tomlValue(val.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		items := make([]string, 0, val.Len())
+		for _, k := range val.MapKeys() {
+			items = append(items, fmt.Sprintf("%s = %s", tomlKey(fmt.Sprint(k.Interface())), tomlValue(val.MapIndex(k))))
+		}
+		sort.Strings(items)
+		if len(items) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	case reflect.Ptr:
+		if val.IsNil() {
+			return `""`
+		}
+		return tomlValue(val.Elem())
+	default:
+		return strconv.Quote(fmt.Sprint(val.Interface()))
+	}
+}
+
+// text returns the string form of a value decoded by a decode hook
+func text(val reflect.Value) string {
+	p := reflect.New(val.Type())
+	p.Elem().Set(val)
+	switch i := p.Interface().(type) {
+	case encoding.TextMarshaler:
+		b, err := i.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	case fmt.Stringer:
+		return i.String()
+	}
+	return fmt.Sprint(val.Interface())
+}
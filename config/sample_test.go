@@ -0,0 +1,174 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+type sampleConfig struct {
+	Name   string `desc:"Service name" validate:"required"`
+	Debug  bool   `default:"true"`
+	Token  string `secret:"true" default:"token"`
+	Labels map[string]string
+	Log    struct {
+		Level string `desc:"Log level" default:"info" validate:"oneof=debug info"`
+	}
+	Server struct {
+		Timeout time.Duration `default:"5s"`
+		Hosts   []string      `default:"a,b"`
+		Port    int           `validate:"min=1,max=65535"`
+		Workers int           `validate:"omitempty,min=2"`
+	}
+	Brokers []struct {
+		Host string `desc:"Broker address"`
+	} `desc:"Kafka brokers"`
+}
+
+func TestWriteSample(t *testing.T) {
+	b := &bytes.Buffer{}
+	err := config.WriteSample(b, config.ViperWithDefaults("app"), &sampleConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `# Service name
+# env: APP_NAME
+# required
+# name = ""
+
+# env: APP_DEBUG
+debug = true
+
+# env: APP_TOKEN
+# token = ""
+
+# env: APP_LABELS_<KEY>
+# labels = {}
+
+[log]
+
+# Log level
+# env: APP_LOG_LEVEL
+level = "info"
+
+[server]
+
+# env: APP_SERVER_TIMEOUT
+timeout = "5s"
+
+# env: APP_SERVER_HOSTS
+hosts = ["a", "b"]
+
+# env: APP_SERVER_PORT
+# required
+# port = 0
+
+# env: APP_SERVER_WORKERS
+# workers = 0
+
+# Kafka brokers
+# env: APP_BROKERS_<N>_HOST
+# [[brokers]]
+# host = ""
+
+`, b.String())
+
+	// the sample is a valid config file setting the defaults
+	v := config.ViperWithDefaults("app")
+	v.SetConfigType("toml")
+	err = v.ReadConfig(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "info", v.GetString("log.level"))
+	assert.Equal(t, []interface{}{"a", "b"}, v.Get("server.hosts"))
+}
+
+func TestWriteSample_Elements(t *testing.T) {
+	type DB struct {
+		Host string
+		Pool int `default:"10"`
+	}
+	type Config struct {
+		DBs map[string]DB `mapstructure:"dbs"`
+	}
+	b := &bytes.Buffer{}
+	err := config.WriteSample(b, config.ViperWithDefaults("app"), &Config{
+		DBs: map[string]DB{"read": {Host: "read:5432"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `# env: APP_DBS_<NAME>_HOST, APP_DBS_<NAME>_POOL
+[dbs.read]
+host = "read:5432"
+pool = 10
+
+`, b.String())
+}
+
+func TestWriteSchema(t *testing.T) {
+	b := &bytes.Buffer{}
+	err := config.WriteSchema(b, &sampleConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s map[string]interface{}
+	err = json.Unmarshal(b.Bytes(), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := s["properties"].(map[string]interface{})
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", s["$schema"])
+	assert.Equal(t, []interface{}{"name"}, s["required"])
+	assert.Equal(t, map[string]interface{}{
+		"type":        "string",
+		"description": "Service name",
+	}, props["name"])
+	assert.Equal(t, map[string]interface{}{
+		"type":      "string",
+		"writeOnly": true,
+	}, props["token"])
+	assert.Equal(t, map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}, props["labels"])
+	assert.Equal(t, map[string]interface{}{
+		"type":        "string",
+		"description": "Log level",
+		"default":     "info",
+		"enum":        []interface{}{"debug", "info"},
+	}, props["log"].(map[string]interface{})["properties"].(map[string]interface{})["level"])
+	server := props["server"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, "5s", server["timeout"].(map[string]interface{})["default"])
+	assert.Equal(t, map[string]interface{}{
+		"type":    "integer",
+		"minimum": float64(1),
+		"maximum": float64(65535),
+	}, server["port"])
+	assert.Equal(t, []interface{}{"port"}, props["server"].(map[string]interface{})["required"])
+	assert.Equal(t, map[string]interface{}{
+		"type": "integer",
+		"anyOf": []interface{}{
+			map[string]interface{}{"const": float64(0)},
+			map[string]interface{}{"minimum": float64(2)},
+		},
+	}, server["workers"])
+	assert.Equal(t, map[string]interface{}{
+		"type":        "array",
+		"description": "Kafka brokers",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"host": map[string]interface{}{
+					"type":        "string",
+					"description": "Broker address",
+				},
+			},
+		},
+	}, props["brokers"])
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schemaURI identifies the JSON Schema draft written by WriteSchema
+const schemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches durations accepted by time.ParseDuration
+const durationPattern = `^(0|[-+]?([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	urlType  = reflect.TypeOf(url.URL{})
+)
+
+// WriteSchema writes a JSON Schema describing the struct c. Properties are
+// named by configuration key and described by the `desc` struct tag, values
+// held by c or `default` struct tags are given as defaults and `validate`
+// struct tag rules are mapped to their schema keywords where possible.
+//
+// Example:
+//
+//	err := config.WriteSchema(os.Stdout, &Config{})
+func WriteSchema(w io.Writer, c interface{}) error {
+	s := objectSchema(reflect.Indirect(reflect.ValueOf(c)))
+	s["$schema"] = schemaURI
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+type schema = map[string]interface{}
+
+// objectSchema builds the schema of a struct value from its leaf fields,
+// nested keys become nested objects
+func objectSchema(val reflect.Value) schema {
+	root := schema{"type": "object", "properties": schema{}}
+	// walkValue errors are only returned by fn
+	_ = walkValue(val, func(f field) error {
+		parts := strings.Split(f.key, ".")
+		obj := root
+		for _, p := range parts[:len(parts)-1] {
+			props := obj["properties"].(schema)
+			sub, ok := props[p].(schema)
+			if !ok {
+				sub = schema{"type": "object", "properties": schema{}}
+				props[p] = sub
+			}
+			obj = sub
+		}
+		name := parts[len(parts)-1]
+		obj["properties"].(schema)[name] = fieldSchema(f)
+		if isRequired(f) {
+			req, _ := obj["required"].([]string)
+			obj["required"] = append(req, name)
+		}
+		return nil
+	})
+	return root
+}
+
+// fieldSchema builds the schema of a leaf field
+func fieldSchema(f field) schema {
+	s := typeSchema(f.value.Type())
+	if d := f.tag.Get("desc"); d != "" {
+		s["description"] = d
+	}
+	if isSecret(f) {
+		s["writeOnly"] = true
+	}
+	if val, ok := sampleValue(f); ok {
+		s["default"] = jsonValue(val)
+	}
+	for _, rule := range strings.Split(f.tag.Get("validate"), ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		ruleSchema(s, f.value.Type(), name, arg)
+	}
+	if omitsEmpty(f) {
+		omitEmptySchema(s)
+	}
+	return s
+}
+
+// boundKeywords are the schema keywords of min and max rules
+var boundKeywords = []string{
+	"minimum", "maximum", "minLength", "maxLength",
+	"minItems", "maxItems", "minProperties", "maxProperties",
+}
+
+// omitEmptySchema moves the bounds of s into an anyOf which also accepts
+// the zero value, as the omitempty rule skips them for the zero value
+func omitEmptySchema(s schema) {
+	bounds := schema{}
+	for _, kw := range boundKeywords {
+		if v, ok := s[kw]; ok {
+			bounds[kw] = v
+			delete(s, kw)
+		}
+	}
+	if len(bounds) == 0 {
+		return
+	}
+	empty := map[string]schema{
+		"number":  {"const": 0},
+		"integer": {"const": 0},
+		"string":  {"maxLength": 0},
+		"array":   {"maxItems": 0},
+		"object":  {"maxProperties": 0},
+	}
+	typ, _ := s["type"].(string)
+	s["anyOf"] = []schema{empty[typ], bounds}
+}
+
+// typeSchema builds the schema of a type, types with a decode hook are
+// strings
+func typeSchema(t reflect.Type) schema {
+	if t.Kind() == reflect.Ptr {
+		return typeSchema(t.Elem())
+	}
+	switch {
+	case t == durationType:
+		return schema{"type": "string", "pattern": durationPattern}
+	case t == timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case t == urlType:
+		return schema{"type": "string", "format": "uri"}
+	case isLeaf(t):
+		return schema{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return objectSchema(reflect.New(t).Elem())
+	default:
+		return schema{}
+	}
+}
+
+// ruleSchema adds the schema keywords for a validation rule, rules without
+// an equivalent keyword are ignored
+func ruleSchema(s schema, t reflect.Type, name, arg string) {
+	switch name {
+	case "min", "max":
+		if t == durationType {
+			return
+		}
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return
+		}
+		kw := map[string]string{
+			"number": "imum",
+			"string": "Length",
+			"array":  "Items",
+			"object": "Properties",
+		}
+		typ, _ := s["type"].(string)
+		if typ == "integer" {
+			typ = "number"
+		}
+		if suffix, ok := kw[typ]; ok {
+			s[name+suffix] = n
+		}
+	case "oneof":
+		var enum []interface{}
+		for _, o := range strings.Fields(arg) {
+			val, err := parseValue(t, o)
+			if err != nil {
+				return
+			}
+			enum = append(enum, jsonValue(val))
+		}
+		s["enum"] = enum
+	case "url":
+		s["format"] = "uri"
+	}
+}
+
+// jsonValue converts a value to its JSON form, types with a decode hook are
+// converted to strings
+func jsonValue(val reflect.Value) interface{} {
+	if isLeaf(val.Type()) {
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return nil
+			}
+			val = val.Elem()
+		}
+		return text(val)
+	}
+	if val.Kind() == reflect.Slice {
+		items := make([]interface{}, val.Len())
+		for i := range items {
+			items[i] = jsonValue(val.Index(i))
+		}
+		return items
+	}
+	return val.Interface()
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A SecretResolver resolves a secret reference such as
+// `file:///var/run/secrets/db/password` to the secret value
+type SecretResolver interface {
+	Resolve(ref *url.URL) (string, error)
+}
+
+// The SecretResolverFunc type is an adapter to allow the use of
+// ordinary functions as a SecretResolver
+type SecretResolverFunc func(ref *url.URL) (string, error)
+
+// Resolve calls f(ref)
+func (f SecretResolverFunc) Resolve(ref *url.URL) (string, error) {
+	return f(ref)
+}
+
+var (
+	resolversMu sync.RWMutex // protects resolvers
+	resolvers   = map[string]SecretResolver{
+		"file": SecretResolverFunc(resolveFile),
+		"env":  SecretResolverFunc(resolveEnv),
+	}
+)
+
+// RegisterSecretResolver makes a SecretResolver available for secret
+// references with the given URL scheme, replacing any resolver already
+// registered for the scheme. The `file` and `env` schemes are registered by
+// default.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = r
+}
+
+// A SecretError describes a secret reference which could not be resolved
+type SecretError struct {
+	Key string // dotted configuration key e.g. `psql.pass`
+	Ref string // secret reference e.g. `env://DB_PASS`
+	Err error
+}
+
+func (e *SecretError) Error() string {
+	return fmt.Sprintf("could not resolve secret %s from %s: %v", e.Key, e.Ref, e.Err)
+}
+
+func (e *SecretError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveSecrets replaces secret references in the string fields of the
+// struct c tagged `secret:"true"` with the value returned by the
+// SecretResolver registered for the reference scheme. Values which are not a
+// reference with a registered scheme are left as they are. Every reference
+// which fails to resolve is reported as a *SecretError.
+//
+// Example:
+//
+//	type Config struct {
+//		// e.g. `file:///var/run/secrets/db/password` or `env://DB_PASS`
+//		Pass string `secret:"true"`
+//	}
+func ResolveSecrets(c interface{}) error {
+	var errs []error
+	err := walkSetFields(c, func(f field) error {
+		if !isSecret(f) || f.value.Kind() != reflect.String {
+			return nil
+		}
+		s, err := resolveSecret(f.key, f.value.String())
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		f.value.SetString(s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// resolveSecret returns the secret referenced by the value of key, values
+// which are not a reference are returned as they are
+func resolveSecret(key, ref string) (string, error) {
+	u, r := secretResolver(ref)
+	if r == nil {
+		return ref, nil
+	}
+	s, err := r.Resolve(u)
+	if err != nil {
+		return "", &SecretError{Key: key, Ref: ref, Err: err}
+	}
+	return s, nil
+}
+
+// secretFieldKeys returns the lowercased keys of the string fields of c
+// tagged as holding a secret
+func secretFieldKeys(c interface{}) map[string]bool {
+	keys := map[string]bool{}
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		if isSecret(f) && f.value.Kind() == reflect.String {
+			keys[strings.ToLower(f.key)] = true
+		}
+		return nil
+	})
+	return keys
+}
+
+// isSecret returns true if a field is tagged as holding a secret
+func isSecret(f field) bool {
+	return f.tag.Get("secret") == "true"
+}
+
+// secretResolver returns the parsed reference and resolver for a secret
+// reference, the resolver is nil if s is not a reference
+func secretResolver(s string) (*url.URL, SecretResolver) {
+	if !strings.Contains(s, "://") {
+		return nil, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, nil
+	}
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	return u, resolvers[u.Scheme]
+}
+
+// resolveFile reads a secret from a file e.g. a mounted Kubernetes secret,
+// trailing new lines are trimmed
+func resolveFile(ref *url.URL) (string, error) {
+	b, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// resolveEnv reads a secret from an env var
+func resolveEnv(ref *url.URL) (string, error) {
+	s, ok := os.LookupEnv(ref.Host)
+	if !ok {
+		return "", fmt.Errorf("env var %s not set", ref.Host)
+	}
+	return s, nil
+}
@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	type DB struct {
+		User string `secret:"true"`
+		Pass string `secret:"true"`
+		Key  string `secret:"true"`
+		Host string
+	}
+	type Config struct {
+		DB DB
+	}
+
+	p := filepath.Join(t.TempDir(), "password")
+	writeFile(t, p, "s3cret\n")
+	t.Setenv("SECRETS_TEST_KEY", "key")
+	config.RegisterSecretResolver("test", config.SecretResolverFunc(func(ref *url.URL) (string, error) {
+		return "user-" + ref.Host, nil
+	}))
+
+	c := Config{
+		DB: DB{
+			User: "test://admin",
+			Pass: "file://" + p,
+			Key:  "env://SECRETS_TEST_KEY",
+			Host: "env://SECRETS_TEST_KEY",
+		},
+	}
+	err := config.ResolveSecrets(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Config{
+		DB: DB{
+			User: "user-admin",
+			Pass: "s3cret",
+			Key:  "key",
+			Host: "env://SECRETS_TEST_KEY", // not tagged secret
+		},
+	}, c)
+}
+
+func TestResolveSecrets_Errors(t *testing.T) {
+	type Config struct {
+		Pass    string `secret:"true"`
+		Key     string `secret:"true"`
+		Literal string `secret:"true"`
+	}
+	c := Config{
+		Pass:    "file:///does/not/exist",
+		Key:     "env://SECRETS_TEST_MISSING",
+		Literal: "unknown://value",
+	}
+	err := config.ResolveSecrets(&c)
+	var serr *config.SecretError
+	if !errors.As(err, &serr) {
+		t.Fatalf("unexpected error type; expected *config.SecretError, got %T", err)
+	}
+	assert.Equal(t, "pass", serr.Key)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.Contains(t, err.Error(), "could not resolve secret key from env://SECRETS_TEST_MISSING: env var SECRETS_TEST_MISSING not set")
+	assert.Equal(t, "unknown://value", c.Literal)
+}
+
+func TestReadInConfig_Secrets(t *testing.T) {
+	type Log struct {
+		Level string `secret:"true" validate:"oneof=warn"`
+	}
+	type Config struct {
+		Log Log
+	}
+	t.Setenv("SECRETSREAD_LOG_LEVEL", "env://SECRETS_TEST_LEVEL")
+	t.Setenv("SECRETS_TEST_LEVEL", "warn")
+	c := Config{}
+	v := config.ViperWithDefaults("secrets-read")
+	err := config.ReadInConfig(v, &c, config.WithFile("testdata/test.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "warn", c.Log.Level)
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// A RemoteSource provides configuration settings from outside of local
+// config files, e.g. a remote config service. Settings loaded from sources
+// are merged over config files in the order the sources were added, env vars
+// and flags still take precedence.
+type RemoteSource interface {
+	// Name identifies the source, it is reported as the origin of its
+	// settings by Report.Explain
+	Name() string
+	// Load returns the settings of the source as nested maps
+	Load(ctx context.Context) (map[string]interface{}, error)
+}
+
+// WithSource returns an Option merging the settings of src over local config
+// files. Sources are loaded once per read, see WithSourceTimeout.
+//
+// Example:
+//
+//	src := config.NewHTTPSource("http://config.internal/app.toml")
+//	err := config.ReadInConfig(v, &c, config.WithSource(src))
+func WithSource(src RemoteSource) Option {
+	return withState(func(s *state) {
+		s.sources = append(s.sources, src)
+	})
+}
+
+// WithSourceTimeout returns an Option bounding the time taken to load the
+// sources of a read, by default loading is only bounded by the sources
+// themselves e.g. the HTTPSource client timeout
+func WithSourceTimeout(d time.Duration) Option {
+	return withState(func(s *state) {
+		s.sourceTimeout = d
+	})
+}
+
+// A sourced holds the settings loaded from a RemoteSource
+type sourced struct {
+	name     string
+	settings map[string]interface{}
+	keys     map[string]bool
+}
+
+// loadSources loads the settings of the sources added to the read
+func (s *state) loadSources() error {
+	ctx := context.Background()
+	if s.sourceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.sourceTimeout)
+		defer cancel()
+	}
+	s.sourced = nil
+	for _, src := range s.sources {
+		m, err := src.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %v", src.Name(), err)
+		}
+		// pass through viper so keys are lowercased as for files
+		sv := viper.New()
+		err = sv.MergeConfigMap(m)
+		if err != nil {
+			return fmt.Errorf("%s: %v", src.Name(), err)
+		}
+		s.sourced = append(s.sourced, sourced{
+			name:     src.Name(),
+			settings: sv.AllSettings(),
+			keys:     keySet(sv.AllKeys()),
+		})
+	}
+	return nil
+}
+
+// sourceSettings returns the merged settings loaded from sources
+func (s *state) sourceSettings() map[string]interface{} {
+	settings := map[string]interface{}{}
+	for _, src := range s.sourced {
+		mergeMaps(settings, src.settings)
+	}
+	return settings
+}
+
+// conformMaps converts scalar values in src to the type of the values they
+// replace in dst, viper.MergeConfigMap skips values of a different type and
+// formats decode numbers to different types
+func conformMaps(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		switch dv := dst[k].(type) {
+		case map[string]interface{}:
+			if sm, ok := sv.(map[string]interface{}); ok {
+				conformMaps(dv, sm)
+			}
+		case string:
+			src[k] = cast.ToString(sv)
+		case bool:
+			src[k] = cast.ToBool(sv)
+		case int:
+			src[k] = cast.ToInt(sv)
+		case int64:
+			src[k] = cast.ToInt64(sv)
+		case float64:
+			src[k] = cast.ToFloat64(sv)
+		}
+	}
+}
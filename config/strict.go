@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Strict returns an Option failing ReadInConfig and ReadInAllDirConfig with
+// an *UnknownKeysError when a config file or remote source sets keys which
+// are not fields of the configuration struct, e.g. a misspelt
+// `levle = "debug"`.
+func Strict() Option {
+	return withState(func(s *state) {
+		s.strict = true
+	})
+}
+
+// StrictEnv returns an Option checking that set env vars with the service
+// prefix, e.g. `NAME_`, are bound to a configuration key. Unknown env vars
+// are reported in an *UnknownKeysError, which is passed to warn if given,
+// otherwise ReadInConfig and ReadInAllDirConfig fail with it.
+//
+// Example:
+//
+//	config.StrictEnv(func(err error) {
+//		log.Warn().Err(err).Msg("ignored env vars")
+//	})
+func StrictEnv(warn func(err error)) Option {
+	return withState(func(s *state) {
+		s.strictEnv = true
+		s.envWarn = warn
+	})
+}
+
+// An UnknownKey is a config file key or env var which does not map to a
+// field of the configuration struct
+type UnknownKey struct {
+	Key        string // config file key or env var name
+	Origin     string // config file, remote source or dotenv file, or `env`
+	Suggestion string // closest known key or env var, if any
+}
+
+func (k UnknownKey) String() string {
+	s := fmt.Sprintf("%s (%s)", k.Key, k.Origin)
+	if k.Suggestion != "" {
+		s += fmt.Sprintf(", did you mean %s?", k.Suggestion)
+	}
+	return s
+}
+
+// An UnknownKeysError lists the unknown keys found by Strict or StrictEnv
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	msgs := make([]string, len(e.Keys))
+	for i, k := range e.Keys {
+		msgs[i] = k.String()
+	}
+	return fmt.Sprintf("unknown configuration keys: %s", strings.Join(msgs, "; "))
+}
+
+// checkStrict reports unknown config file keys and env vars when enabled by
+// Strict or StrictEnv
+func (s *state) checkStrict(v *viper.Viper, c interface{}) error {
+	if !s.strict && !s.strictEnv {
+		return nil
+	}
+	var (
+		keys     []string // known leaf keys
+		prefixes []string // keys of maps and slices of structs
+	)
+	// walkFields errors are only returned by fn
+	_ = walkFields(c, func(f field) error {
+		k := strings.ToLower(f.key)
+		keys = append(keys, k)
+		if hasEntries(f) {
+			prefixes = append(prefixes, k+".")
+		}
+		return nil
+	})
+	if s.strict {
+		files := s.files
+		for _, src := range s.sourced {
+			files = append(files, configFile{path: src.name, keys: src.keys})
+		}
+		err := unknownFileKeys(files, keys, prefixes)
+		if err != nil {
+			return err
+		}
+	}
+	if s.strictEnv && envPrefix(v) != "" {
+		err := s.unknownEnvs(v, keys, prefixes)
+		switch {
+		case err != nil && s.envWarn != nil:
+			s.envWarn(err)
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// unknownFileKeys returns an *UnknownKeysError for keys set by config files
+// which are not known
+func unknownFileKeys(files []configFile, keys, prefixes []string) error {
+	known := map[string]bool{}
+	for _, k := range keys {
+		known[k] = true
+	}
+	verr := &UnknownKeysError{}
+	for _, f := range files {
+		for _, k := range sortedKeys(f.keys) {
+			if known[k] || hasAnyPrefix(k, prefixes) {
+				continue
+			}
+			verr.Keys = append(verr.Keys, UnknownKey{
+				Key:        k,
+				Origin:     f.path,
+				Suggestion: suggest(k, keys),
+			})
+		}
+	}
+	if len(verr.Keys) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// unknownEnvs returns an *UnknownKeysError for set env vars and dotenv vars
+// with the service prefix which are not bound to a known key
+func (s *state) unknownEnvs(v *viper.Viper, keys, prefixes []string) error {
+	// suggestions are made on names without the shared prefix
+	prefix := envName(v, "")
+	names := []string{"PROFILE"}
+	known := map[string]bool{prefix + names[0]: true}
+	for _, k := range keys {
+		name := envName(v, k)
+		known[name] = true
+		names = append(names, strings.TrimPrefix(name, prefix))
+	}
+	envPrefixes := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		envPrefixes[i] = envName(v, strings.TrimSuffix(p, ".")) + "_"
+	}
+	verr := &UnknownKeysError{}
+	for _, name := range s.environ(prefix) {
+		if known[name] || hasAnyPrefix(name, envPrefixes) {
+			continue
+		}
+		k := UnknownKey{Key: name, Origin: "env"}
+		if _, ok := os.LookupEnv(name); !ok {
+			k.Origin = s.dotEnv[name].path
+		}
+		if s := suggest(strings.TrimPrefix(name, prefix), names); s != "" {
+			k.Suggestion = prefix + s
+		}
+		verr.Keys = append(verr.Keys, k)
+	}
+	if len(verr.Keys) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the candidate closest to s by edit distance, or an empty
+// string if none are close enough to be a likely typo
+func suggest(s string, candidates []string) string {
+	best, bestDist := "", len(s)/3+2
+	for _, c := range candidates {
+		if d := editDistance(s, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
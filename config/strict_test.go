@@ -0,0 +1,89 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+type strictConfig struct {
+	Log struct {
+		Level string
+	}
+	Labels  map[string]string
+	Brokers []struct {
+		Host string
+	}
+}
+
+func TestStrict(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "strict.toml")
+	writeFile(t, p, `
+[log]
+levle = "debug"
+
+[labels]
+team = "platform"
+
+[[brokers]]
+host = "a:9092"
+`)
+	var c strictConfig
+	err := config.ReadInConfig(config.ViperWithDefaults("strict"), &c, config.WithFile(p))
+	assert.NoError(t, err, "unknown keys are ignored by default")
+
+	err = config.ReadInConfig(config.ViperWithDefaults("strict"), &c, config.WithFile(p), config.Strict())
+	var uerr *config.UnknownKeysError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected UnknownKeysError, got %v", err)
+	}
+	assert.Equal(t, []config.UnknownKey{
+		{Key: "log.levle", Origin: p, Suggestion: "log.level"},
+	}, uerr.Keys)
+	assert.EqualError(t, err, "unknown configuration keys: log.levle ("+p+"), did you mean log.level?")
+
+	// options apply to a single read of a viper instance
+	v := config.ViperWithDefaults("strict")
+	err = config.ReadInConfig(v, &c, config.WithFile(p), config.Strict())
+	assert.True(t, errors.As(err, &uerr))
+	err = config.ReadInConfig(v, &c)
+	assert.NoError(t, err)
+	// options configuring the read do nothing when applied to v directly
+	assert.NoError(t, config.Strict()(v))
+	err = config.ReadInConfig(v, &c)
+	assert.NoError(t, err)
+}
+
+func TestStrictEnv(t *testing.T) {
+	t.Setenv("STRICTENV_LOG_LEVLE", "debug")
+	t.Setenv("STRICTENV_LABELS_TEAM", "platform")
+	t.Setenv("STRICTENV_BROKERS_0_HOST", "a:9092")
+	t.Setenv("STRICTENV_PROFILE", "staging")
+	t.Setenv("STRICTENV_UNRELATED", "x")
+	want := []config.UnknownKey{
+		{Key: "STRICTENV_LOG_LEVLE", Origin: "env", Suggestion: "STRICTENV_LOG_LEVEL"},
+		{Key: "STRICTENV_UNRELATED", Origin: "env"},
+	}
+
+	var c strictConfig
+	err := config.ReadInConfig(config.ViperWithDefaults("strictenv"), &c, config.StrictEnv(nil))
+	var uerr *config.UnknownKeysError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected UnknownKeysError, got %v", err)
+	}
+	assert.Equal(t, want, uerr.Keys)
+
+	var warned error
+	err = config.ReadInConfig(config.ViperWithDefaults("strictenv"), &c, config.StrictEnv(func(err error) {
+		warned = err
+	}))
+	assert.NoError(t, err)
+	if !errors.As(warned, &uerr) {
+		t.Fatalf("expected UnknownKeysError warning, got %v", warned)
+	}
+	assert.Equal(t, want, uerr.Keys)
+	assert.Equal(t, "platform", c.Labels["team"])
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// A Validator can be implemented by a configuration struct to reject a
+// loaded configuration, it is called by Validate once the struct tag rules
+// pass. ReadInConfig and ReadInAllDirConfig only call it with WithValidator,
+// a Watcher will then keep the last good configuration when Validate returns
+// an error.
+type Validator interface {
+	Validate() error
+}
+
+// WithValidator returns an Option calling the Validate method of
+// configuration structs implementing Validator once the struct tag rules
+// pass
+func WithValidator() Option {
+	return withState(func(s *state) {
+		s.validator = true
+	})
+}
+
+// A FieldError describes a configuration key which failed validation
+type FieldError struct {
+	Key  string // dotted configuration key e.g. `log.level`
+	Env  string // env var which sets the key e.g. `NAME_LOG_LEVEL`
+	Rule string // failing validation rule e.g. `required`
+	Msg  string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Key, e.Env, e.Msg)
+}
+
+// A ValidationError lists every configuration key which failed validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(msgs, "; "))
+}
+
+// Validate checks the fields of the struct c against the rules in their
+// `validate` struct tags, nested structs are walked the same way as for env
+// var binding, except nil pointers to structs which are optional sections
+// left unset. All failing keys are returned in a *ValidationError. If c
+// implements Validator its Validate method is called once the tag rules pass.
+//
+// Rules are comma separated, rules other than required, min and max are
+// only checked for non-zero values:
+//   - required: must not be the zero value
+//   - omitempty: no other rule is checked for the zero value, e.g. for an
+//     optional port `omitempty,min=1`
+//   - min=n, max=n: numeric bounds, length bounds for strings, slices and
+//     maps, or duration bounds for time.Duration e.g. `min=1s`
+//   - oneof=a b c: must be one of the space separated values
+//   - url: must be an absolute URL with a scheme and host
+//   - hostport: must be a `host:port` address, host may be empty e.g. `:5000`
+//
+// Example:
+//
+//	type Config struct {
+//		Host    string        `validate:"required,hostport"`
+//		Level   string        `validate:"oneof=debug info warn error"`
+//		Timeout time.Duration `validate:"min=1s,max=1m"`
+//	}
+func Validate(v *viper.Viper, c interface{}) error {
+	err := validateTags(v, c)
+	if err != nil {
+		return err
+	}
+	if vc, ok := c.(Validator); ok {
+		return vc.Validate()
+	}
+	return nil
+}
+
+// validateTags checks the fields of the struct c against the rules in their
+// `validate` struct tags
+func validateTags(v *viper.Viper, c interface{}) error {
+	verr := &ValidationError{}
+	// unset optional sections are not validated
+	err := walkSetFields(c, func(f field) error {
+		rules := f.tag.Get("validate")
+		if rules == "" || (omitsEmpty(f) && f.value.IsZero()) {
+			return nil
+		}
+		for _, rule := range strings.Split(rules, ",") {
+			name, arg := rule, ""
+			if i := strings.Index(rule, "="); i >= 0 {
+				name, arg = rule[:i], rule[i+1:]
+			}
+			msg, err := checkRule(f.value, name, arg)
+			if err != nil {
+				return fmt.Errorf("%s: %v", f.key, err)
+			}
+			if msg != "" {
+				verr.Fields = append(verr.Fields, FieldError{
+					Key:  f.key,
+					Env:  envName(v, f.key),
+					Rule: name,
+					Msg:  msg,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// checkRule checks a value against a validation rule returning a failure
+// message, an error is returned for malformed rules
+func checkRule(val reflect.Value, name, arg string) (string, error) {
+	switch name {
+	case "required":
+		if val.IsZero() {
+			return "is required", nil
+		}
+		return "", nil
+	case "omitempty":
+		return "", nil
+	case "min", "max":
+		return checkBound(val, name, arg)
+	}
+	if val.IsZero() {
+		return "", nil
+	}
+	switch name {
+	case "oneof":
+		s := fmt.Sprint(val.Interface())
+		for _, o := range strings.Fields(arg) {
+			if s == o {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(arg), ", ")), nil
+	case "url":
+		u, err := url.Parse(fmt.Sprint(val.Interface()))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid url", nil
+		}
+		return "", nil
+	case "hostport":
+		_, port, err := net.SplitHostPort(fmt.Sprint(val.Interface()))
+		if err != nil {
+			return "must be a host:port address", nil
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "must have a valid port", nil
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("unknown validation rule %q", name)
+	}
+}
+
+// omitsEmpty returns true for fields with the omitempty rule
+func omitsEmpty(f field) bool {
+	for _, rule := range strings.Split(f.tag.Get("validate"), ",") {
+		if rule == "omitempty" {
+			return true
+		}
+	}
+	return false
+}
+
+// zeroFails returns true if the zero value of a field fails its min or max
+// rules, the field must then be set
+func zeroFails(f field) bool {
+	if omitsEmpty(f) {
+		return false
+	}
+	zero := reflect.Zero(f.value.Type())
+	for _, rule := range strings.Split(f.tag.Get("validate"), ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name != "min" && name != "max" {
+			continue
+		}
+		if msg, err := checkBound(zero, name, arg); err == nil && msg != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBound checks a min or max rule against a value
+func checkBound(val reflect.Value, name, arg string) (string, error) {
+	var n, bound float64
+	var desc string
+	switch {
+	case val.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s duration %q", name, arg)
+		}
+		n, bound, desc = float64(val.Int()), float64(d), d.String()
+	default:
+		b, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s value %q", name, arg)
+		}
+		bound, desc = b, arg
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(val.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(val.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = val.Float()
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			n = float64(val.Len())
+			desc = fmt.Sprintf("length %s", arg)
+		default:
+			return "", fmt.Errorf("%s rule not supported for %s", name, val.Kind())
+		}
+	}
+	switch {
+	case name == "min" && n < bound:
+		return fmt.Sprintf("must be at least %s", desc), nil
+	case name == "max" && n > bound:
+		return fmt.Sprintf("must be at most %s", desc), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,150 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.soon.build/kit/config"
+)
+
+func TestValidate(t *testing.T) {
+	type DB struct {
+		Host string `validate:"required,hostport"`
+		Pool int    `validate:"min=1,max=10"`
+	}
+	type Config struct {
+		Database DB
+		Project  string        `mapstructure:"projectID" validate:"required"`
+		Level    string        `validate:"oneof=debug info"`
+		Endpoint string        `validate:"url"`
+		Timeout  time.Duration `validate:"min=1s,max=1m"`
+		Tags     []string      `validate:"max=2"`
+		Workers  int           `validate:"omitempty,min=2"`
+	}
+
+	tests := map[string]struct {
+		config Config
+		want   []config.FieldError
+	}{
+		"valid": {
+			config: Config{
+				Database: DB{Host: "localhost:5432", Pool: 5},
+				Project:  "project",
+				Level:    "info",
+				Endpoint: "https://example.com",
+				Timeout:  time.Second * 5,
+			},
+		},
+		"invalid": {
+			config: Config{
+				Database: DB{Host: "localhost", Pool: 20},
+				Level:    "trace",
+				Endpoint: "example",
+				Timeout:  time.Minute * 2,
+				Tags:     []string{"a", "b", "c"},
+				Workers:  1,
+			},
+			want: []config.FieldError{
+				{Key: "database.host", Env: "VALIDATE_DATABASE_HOST", Rule: "hostport", Msg: "must be a host:port address"},
+				{Key: "database.pool", Env: "VALIDATE_DATABASE_POOL", Rule: "max", Msg: "must be at most 10"},
+				{Key: "projectID", Env: "VALIDATE_PROJECTID", Rule: "required", Msg: "is required"},
+				{Key: "level", Env: "VALIDATE_LEVEL", Rule: "oneof", Msg: "must be one of debug, info"},
+				{Key: "endpoint", Env: "VALIDATE_ENDPOINT", Rule: "url", Msg: "must be a valid url"},
+				{Key: "timeout", Env: "VALIDATE_TIMEOUT", Rule: "max", Msg: "must be at most 1m0s"},
+				{Key: "tags", Env: "VALIDATE_TAGS", Rule: "max", Msg: "must be at most length 2"},
+				{Key: "workers", Env: "VALIDATE_WORKERS", Rule: "min", Msg: "must be at least 2"},
+			},
+		},
+		"zero values": {
+			config: Config{
+				Database: DB{Host: "localhost:5432"},
+				Project:  "project",
+			},
+			want: []config.FieldError{
+				{Key: "database.pool", Env: "VALIDATE_DATABASE_POOL", Rule: "min", Msg: "must be at least 1"},
+				{Key: "timeout", Env: "VALIDATE_TIMEOUT", Rule: "min", Msg: "must be at least 1s"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := config.ViperWithDefaults("validate")
+			err := config.Validate(v, &tt.config)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *config.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("unexpected error type; expected *config.ValidationError, got %T", err)
+			}
+			assert.Equal(t, tt.want, verr.Fields)
+		})
+	}
+}
+
+type validatorConfig struct {
+	Min int
+	Max int
+}
+
+func (c *validatorConfig) Validate() error {
+	if c.Min > c.Max {
+		return errors.New("min must not exceed max")
+	}
+	return nil
+}
+
+func TestValidate_Validator(t *testing.T) {
+	v := viper.New()
+	err := config.Validate(v, &validatorConfig{Min: 2, Max: 1})
+	assert.EqualError(t, err, "min must not exceed max")
+}
+
+func TestReadInConfig_Validate(t *testing.T) {
+	type Log struct {
+		Level string `validate:"required"`
+		Sink  string `validate:"required"`
+	}
+	type Config struct {
+		Log Log
+	}
+	t.Setenv("VALIDATEREAD_LOG_LEVEL", "debug")
+	c := Config{}
+	v := config.ViperWithDefaults("validate-read")
+	err := config.ReadInConfig(v, &c, config.WithFile("testdata/test.toml"))
+	assert.EqualError(t, err, "invalid configuration: log.sink (VALIDATEREAD_LOG_SINK): is required")
+}
+
+func TestReadInConfig_Validator(t *testing.T) {
+	t.Setenv("VALIDATORREAD_MIN", "2")
+	t.Setenv("VALIDATORREAD_MAX", "1")
+	c := validatorConfig{}
+	err := config.ReadInConfig(config.ViperWithDefaults("validatorread"), &c)
+	assert.NoError(t, err, "Validator is opt-in")
+	err = config.ReadInConfig(config.ViperWithDefaults("validatorread"), &c, config.WithValidator())
+	assert.EqualError(t, err, "min must not exceed max")
+}
+
+func TestReadInConfig_ValidateOptional(t *testing.T) {
+	type TLS struct {
+		Cert string `validate:"required"`
+		Key  string `secret:"true"`
+	}
+	type Config struct {
+		TLS *TLS `mapstructure:"tls"`
+	}
+	c := Config{}
+	err := config.ReadInConfig(config.ViperWithDefaults("validateptr"), &c)
+	assert.NoError(t, err, "unset optional sections are not validated")
+	assert.Nil(t, c.TLS)
+
+	t.Setenv("VALIDATEPTR_TLS_KEY", "env://VALIDATEPTR_KEY")
+	t.Setenv("VALIDATEPTR_KEY", "s3cret")
+	err = config.ReadInConfig(config.ViperWithDefaults("validateptr"), &c)
+	assert.EqualError(t, err, "invalid configuration: tls.cert (VALIDATEPTR_TLS_CERT): is required")
+	assert.Equal(t, &TLS{Key: "s3cret"}, c.TLS)
+}
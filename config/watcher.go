@@ -0,0 +1,353 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay is how long a Watcher waits for file events to settle before
+// reloading, editors and Kubernetes configmap updates emit several events
+// for a single change
+const reloadDelay = time.Millisecond * 100
+
+// A Watcher holds a configuration struct of type T and keeps it up to date
+// with the config file(s) it was loaded from. Each reload reads the
+// configuration into a fresh struct which replaces the current one
+// atomically, a failed reload keeps the last good configuration.
+//
+// Example:
+//
+//	w, err := config.NewWatcher[Config](func() *viper.Viper {
+//		return config.ViperWithDefaults("name")
+//	})
+//	if err != nil {
+//		// handle initial load err
+//	}
+//	w.OnChange(func(old, new *Config) {
+//		// apply new configuration
+//	})
+//	w.OnError(func(err error) {
+//		// handle reload err
+//	})
+//	go w.Start(ctx)
+//	c := w.Get()
+type Watcher[T any] struct {
+	load      func(c *T) ([]string, error)
+	current   atomic.Pointer[T]
+	mu        sync.Mutex    // protects the fields below and reloads
+	paths     []string      // files or directories being watched
+	loaded    chan struct{} // signals Start that paths may have changed
+	ready     chan struct{} // closed once Start is watching
+	readyOnce sync.Once     // closes ready
+	changeF   []func(old, new *T)
+	errF      []func(error)
+	pending   []notification[T] // reload results to notify in order
+	notifying bool              // funcs are being called for pending
+}
+
+// A notification is the result of a reload passed to OnChange or OnError
+// funcs
+type notification[T any] struct {
+	prev, next *T
+	err        error
+}
+
+// NewWatcher constructs a Watcher for a config file read with ReadInConfig,
+// newViper is called to construct a fresh viper instance for every load,
+// e.g. with ViperWithDefaults. The configuration is loaded before returning.
+func NewWatcher[T any](newViper func() *viper.Viper, opts ...Option) (*Watcher[T], error) {
+	return newWatcher(func(c *T) ([]string, error) {
+		v := newViper()
+		err := ReadInConfig(v, c, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if v.ConfigFileUsed() == "" {
+			// watch for a config file created in the search paths
+			return lookupPaths(v), nil
+		}
+		return []string{v.ConfigFileUsed()}, nil
+	})
+}
+
+// NewDirWatcher constructs a Watcher for a directory of config files read
+// with ReadInAllDirConfig, newViper is called to construct a fresh viper
+// instance and directory path for every load, e.g. with ViperWithDir. The
+// configuration is loaded before returning.
+func NewDirWatcher[T any](newViper func() (*viper.Viper, string), opts ...Option) (*Watcher[T], error) {
+	return newWatcher(func(c *T) ([]string, error) {
+		v, p := newViper()
+		err := ReadInAllDirConfig(v, p, c, opts...)
+		if err != nil {
+			return nil, err
+		}
+		// watch the directory along with any file read from elsewhere
+		// e.g. with WithFile
+		if v.ConfigFileUsed() != "" {
+			return []string{p, v.ConfigFileUsed()}, nil
+		}
+		return []string{p}, nil
+	})
+}
+
+func newWatcher[T any](load func(c *T) ([]string, error)) (*Watcher[T], error) {
+	w := &Watcher[T]{
+		load:   load,
+		loaded: make(chan struct{}, 1),
+		ready:  make(chan struct{}),
+	}
+	c := new(T)
+	paths, err := w.load(c)
+	if err != nil {
+		return nil, err
+	}
+	w.paths = paths
+	w.current.Store(c)
+	return w, nil
+}
+
+// Get returns the current configuration, the returned value must not be
+// modified as it is shared between callers
+func (w *Watcher[T]) Get() *T {
+	return w.current.Load()
+}
+
+// OnChange registers a func called with the old and new configuration
+// after a reload changes the configuration
+func (w *Watcher[T]) OnChange(fn func(old, new *T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changeF = append(w.changeF, fn)
+}
+
+// OnError registers a func called when a reload fails, the current
+// configuration is left in place
+func (w *Watcher[T]) OnError(fn func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errF = append(w.errF, fn)
+}
+
+// Reload reads the configuration into a fresh struct and swaps it with the
+// current one, OnChange funcs are called if the configuration has changed.
+// On error the current configuration is kept and OnError funcs are called.
+//
+// Funcs are called once the reload has completed, one reload at a time in
+// the order the reloads completed, so they see configurations in order and
+// may call Reload. If funcs of another reload are being called, e.g. when a
+// func calls Reload, Reload returns once its funcs are queued.
+func (w *Watcher[T]) Reload() error {
+	err := w.reload()
+	w.notify()
+	return err
+}
+
+// reload reads the configuration and swaps it with the current one,
+// queueing a notification if it has changed or failed
+func (w *Watcher[T]) reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	next := new(T)
+	paths, err := w.load(next)
+	if err != nil {
+		w.pending = append(w.pending, notification[T]{err: err})
+		return err
+	}
+	w.paths = paths
+	select {
+	case w.loaded <- struct{}{}:
+	default:
+	}
+	prev := w.current.Load()
+	if reflect.DeepEqual(prev, next) {
+		return nil
+	}
+	w.current.Store(next)
+	w.pending = append(w.pending, notification[T]{prev: prev, next: next})
+	return nil
+}
+
+// notify calls the OnChange and OnError funcs for the pending notifications
+// in order, unless they are already being called
+func (w *Watcher[T]) notify() {
+	w.mu.Lock()
+	if w.notifying {
+		w.mu.Unlock()
+		return
+	}
+	w.notifying = true
+	w.mu.Unlock()
+	done := false
+	defer func() {
+		if !done {
+			// a func panicked, later reloads notify the rest
+			w.mu.Lock()
+			w.notifying = false
+			w.mu.Unlock()
+		}
+	}()
+	for {
+		n, changeF, errF, ok := w.next()
+		if !ok {
+			done = true
+			return
+		}
+		if n.err != nil {
+			for _, fn := range errF {
+				fn(n.err)
+			}
+			continue
+		}
+		for _, fn := range changeF {
+			fn(n.prev, n.next)
+		}
+	}
+}
+
+// next removes the first pending notification, returning it along with a
+// copy of the funcs to call. If none are pending notifying is reset.
+func (w *Watcher[T]) next() (notification[T], []func(old, new *T), []func(error), bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		w.notifying = false
+		return notification[T]{}, nil, nil, false
+	}
+	n := w.pending[0]
+	w.pending = w.pending[1:]
+	return n, append([]func(old, new *T){}, w.changeF...), append([]func(error){}, w.errF...), true
+}
+
+// Ready returns a channel closed once Start is watching the config files
+func (w *Watcher[T]) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// watchPaths returns the paths currently being watched
+func (w *Watcher[T]) watchPaths() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paths
+}
+
+// Start watches the config files for changes, reloading the configuration
+// when they are written, will block until ctx is done or the file watcher
+// fails. Files read by a later reload are watched from then on. If no
+// config file was found the viper config paths are watched for one to be
+// created.
+func (w *Watcher[T]) Start(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	dirs := map[string]bool{}
+	paths := w.watchPaths()
+	err = watchDirs(fw, dirs, paths)
+	if err != nil {
+		return err
+	}
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	w.readyOnce.Do(func() {
+		close(w.ready)
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case e, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if watched(paths, e.Name) {
+				timer.Reset(reloadDelay)
+			}
+		case <-timer.C:
+			// errors are reported to OnError funcs
+			_ = w.Reload()
+		case <-w.loaded:
+			paths = w.watchPaths()
+			err = watchDirs(fw, dirs, paths)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchDirs adds the directories of paths which are not in dirs to fw.
+// Parent directories of files are watched so files replaced by a rename or
+// a symlink swap continue to be watched. Directories which do not exist,
+// e.g. unused config paths, are skipped.
+func watchDirs(fw *fsnotify.Watcher, dirs map[string]bool, paths []string) error {
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if isDir(p) {
+			dir = p
+		}
+		if dirs[dir] || !isDir(dir) {
+			continue
+		}
+		err := fw.Add(dir)
+		if err != nil {
+			return err
+		}
+		dirs[dir] = true
+	}
+	return nil
+}
+
+// watched returns true if a file event name relates to one of the watched
+// paths. Kubernetes mounts configmaps through `..data` symlinks, events for
+// those are always considered.
+func watched(paths []string, name string) bool {
+	name = filepath.Clean(name)
+	if strings.HasPrefix(filepath.Base(name), "..") {
+		return true
+	}
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if name == p || filepath.Dir(name) == p {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPaths returns the config files an implicit config file lookup of v
+// would find, in each of its config paths
+func lookupPaths(v *viper.Viper) []string {
+	e := reflect.ValueOf(v).Elem()
+	name, dirs := e.FieldByName("configName"), e.FieldByName("configPaths")
+	if name.Kind() != reflect.String || dirs.Kind() != reflect.Slice {
+		return nil
+	}
+	var paths []string
+	for i := 0; i < dirs.Len(); i++ {
+		for _, ext := range lookupExts {
+			paths = append(paths, filepath.Join(dirs.Index(i).String(), name.String()+ext))
+		}
+	}
+	return paths
+}
+
+func isDir(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,213 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.soon.build/kit/config"
+)
+
+type watchConfig struct {
+	Log struct {
+		Level string
+	}
+}
+
+func (c *watchConfig) Validate() error {
+	if c.Log.Level == "invalid" {
+		return errors.New("invalid log level")
+	}
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "watch.toml")
+	writeFile(t, p, "[log]\nlevel = \"info\"\n")
+
+	w, err := config.NewWatcher[watchConfig](func() *viper.Viper {
+		return config.ViperWithDefaults("watch")
+	}, config.WithFile(p), config.WithValidator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Get().Log.Level != "info" {
+		t.Fatalf("unexpected value for Log.Level; expected %s, got %s", "info", w.Get().Log.Level)
+	}
+	changes := make(chan [2]string, 1)
+	w.OnChange(func(old, new *watchConfig) {
+		changes <- [2]string{old.Log.Level, new.Log.Level}
+	})
+	errs := make(chan error, 1)
+	w.OnError(func(err error) {
+		errs <- err
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := w.Start(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-w.Ready()
+
+	// valid change
+	writeFile(t, p, "[log]\nlevel = \"debug\"\n")
+	select {
+	case c := <-changes:
+		if c[0] != "info" || c[1] != "debug" {
+			t.Errorf("unexpected change; expected [info debug], got %v", c)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for change")
+	}
+
+	// invalid change keeps last good config
+	writeFile(t, p, "[log]\nlevel = \"invalid\"\n")
+	select {
+	case <-errs:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for error")
+	}
+	if w.Get().Log.Level != "debug" {
+		t.Errorf("unexpected value for Log.Level; expected %s, got %s", "debug", w.Get().Log.Level)
+	}
+
+	// unparsable file keeps last good config
+	writeFile(t, p, "[log\n")
+	select {
+	case <-errs:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for error")
+	}
+	if w.Get().Log.Level != "debug" {
+		t.Errorf("unexpected value for Log.Level; expected %s, got %s", "debug", w.Get().Log.Level)
+	}
+}
+
+func TestDirWatcher_Reload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.toml"), "[log]\nlevel = \"info\"\n")
+
+	w, err := config.NewDirWatcher[watchConfig](func() (*viper.Viper, string) {
+		v := viper.New()
+		v.AddConfigPath(dir)
+		return v, dir
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := 0
+	w.OnChange(func(old, new *watchConfig) {
+		called++
+	})
+	// unchanged config does not notify
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if called != 0 {
+		t.Errorf("unexpected OnChange calls; expected %d, got %d", 0, called)
+	}
+	writeFile(t, filepath.Join(dir, "a.toml"), "[log]\nlevel = \"warn\"\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if called != 1 {
+		t.Errorf("unexpected OnChange calls; expected %d, got %d", 1, called)
+	}
+	if w.Get().Log.Level != "warn" {
+		t.Errorf("unexpected value for Log.Level; expected %s, got %s", "warn", w.Get().Log.Level)
+	}
+}
+
+func TestWatcher_NewFile(t *testing.T) {
+	dir := t.TempDir()
+	w, err := config.NewWatcher[watchConfig](func() *viper.Viper {
+		v := config.ViperWithDefaults("watchnew")
+		v.AddConfigPath(dir)
+		return v
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan string, 1)
+	w.OnChange(func(old, new *watchConfig) {
+		changes <- new.Log.Level
+		// funcs may reload without deadlocking
+		_ = w.Reload()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := w.Start(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-w.Ready()
+
+	// a file created after the first load is found and watched
+	p := filepath.Join(dir, "watchnew.toml")
+	writeFile(t, p, "[log]\nlevel = \"info\"\n")
+	expectChange := func(want string) {
+		t.Helper()
+		select {
+		case level := <-changes:
+			if level != want {
+				t.Errorf("unexpected value for Log.Level; expected %s, got %s", want, level)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for change")
+		}
+	}
+	expectChange("info")
+	writeFile(t, p, "[log]\nlevel = \"debug\"\n")
+	expectChange("debug")
+}
+
+func TestWatcher_NotifyOrder(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.toml")
+	writeFile(t, p, "[log]\nlevel = \"info\"\n")
+	w, err := config.NewDirWatcher[watchConfig](func() (*viper.Viper, string) {
+		return viper.New(), dir
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes [][2]string
+	w.OnChange(func(old, new *watchConfig) {
+		if new.Log.Level == "debug" {
+			// reloads by funcs are notified after the funcs return
+			writeFile(t, p, "[log]\nlevel = \"warn\"\n")
+			if err := w.Reload(); err != nil {
+				t.Error(err)
+			}
+		}
+		changes = append(changes, [2]string{old.Log.Level, new.Log.Level})
+	})
+	writeFile(t, p, "[log]\nlevel = \"debug\"\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"info", "debug"}, {"debug", "warn"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("unexpected changes; expected %v, got %v", want, changes)
+	}
+	if w.Get().Log.Level != "warn" {
+		t.Errorf("unexpected value for Log.Level; expected %s, got %s", "warn", w.Get().Log.Level)
+	}
+}
+
+func writeFile(t *testing.T, p, data string) {
+	t.Helper()
+	err := os.WriteFile(p, []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,14 +7,22 @@ import (
 	"google.golang.org/grpc"
 )
 
+// defaultDialTimeout bounds the time NewClient blocks waiting for a
+// connection
+const defaultDialTimeout = time.Second * 5
+
 // NewClient constructs a grpc client connection
 func NewClient(server string, grpcOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return dial(server, defaultDialTimeout, grpcOpts...)
+}
+
+func dial(server string, timeout time.Duration, grpcOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	grpcOpts = append(grpcOpts,
 		// For backwards compatibility, keep these previous, hardcoded options
 		grpc.WithBlock(),
 		grpc.WithInsecure(), // Note: Deprecated in newer versions for grpc.WithTransportCredentials(insecure.NewCredentials())
 	)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	return grpc.DialContext(
 		ctx,
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ServerConfig holds server settings which can be loaded with the kit
+// config package as part of a service configuration
+//
+// Example:
+//
+//	type Config struct {
+//		GRPC grpc.ServerConfig `mapstructure:"grpc"`
+//	}
+//	...
+//	srv := grpc.NewFromConfig(services, c.GRPC)
+type ServerConfig struct {
+	Addr           string `mapstructure:"addr" default:":5000" validate:"hostport" desc:"server listen address"`
+	MaxRecvMsgSize int    `mapstructure:"maxRecvMsgSize" desc:"maximum message size in bytes the server can receive, 0 for the grpc default"`
+	MaxSendMsgSize int    `mapstructure:"maxSendMsgSize" desc:"maximum message size in bytes the server can send, 0 for the grpc default"`
+}
+
+// ServerOptions returns the grpc server options set by c
+func (c ServerConfig) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	return opts
+}
+
+// NewFromConfig creates a new gRPC server from c, opts are applied after
+// the configured settings so they take precedence
+func NewFromConfig(services []RegisterServiceFunc, c ServerConfig, opts ...Option) *Server {
+	cfgOpts := []Option{WithServer(grpc.NewServer(c.ServerOptions()...))}
+	if c.Addr != "" {
+		cfgOpts = append(cfgOpts, WithAddress(c.Addr))
+	}
+	return New(services, append(cfgOpts, opts...)...)
+}
+
+// ClientConfig holds client connection settings which can be loaded with
+// the kit config package
+type ClientConfig struct {
+	Target         string        `mapstructure:"target" validate:"required" desc:"server address to connect to"`
+	DialTimeout    time.Duration `mapstructure:"dialTimeout" default:"5s" desc:"time to wait for the connection to be established"`
+	MaxRecvMsgSize int           `mapstructure:"maxRecvMsgSize" desc:"maximum message size in bytes the client can receive, 0 for the grpc default"`
+	MaxSendMsgSize int           `mapstructure:"maxSendMsgSize" desc:"maximum message size in bytes the client can send, 0 for the grpc default"`
+}
+
+// DialOptions returns the grpc dial options set by c
+func (c ClientConfig) DialOptions() []grpc.DialOption {
+	var opts []grpc.CallOption
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithDefaultCallOptions(opts...)}
+}
+
+// NewClientFromConfig constructs a grpc client connection from c, grpcOpts
+// are added to the configured options
+func NewClientFromConfig(c ClientConfig, grpcOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	timeout := c.DialTimeout
+	if timeout == 0 {
+		timeout = defaultDialTimeout
+	}
+	return dial(c.Target, timeout, append(c.DialOptions(), grpcOpts...)...)
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serve starts a grpc server with a health service on a random local port,
+// returning its address and a func to stop it
+func serve(t *testing.T, opts ...grpc.ServerOption) (string, func()) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	return lis.Addr().String(), srv.Stop
+}
+
+func check(t *testing.T, cc *grpc.ClientConn) error {
+	t.Helper()
+	client := healthpb.NewHealthClient(cc)
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestServerConfig_ServerOptions(t *testing.T) {
+	if opts := (ServerConfig{}).ServerOptions(); len(opts) != 0 {
+		t.Errorf("unexpected options for zero config; got %d, want 0", len(opts))
+	}
+	opts := ServerConfig{MaxRecvMsgSize: 1, MaxSendMsgSize: 1}.ServerOptions()
+	if len(opts) != 2 {
+		t.Fatalf("unexpected options; got %d, want 2", len(opts))
+	}
+	addr, stop := serve(t, opts...)
+	defer stop()
+	cc, err := NewClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	// the health response is larger than the configured send size
+	if code := status.Code(check(t, cc)); code != codes.ResourceExhausted {
+		t.Errorf("unexpected status code; got %v, want %v", code, codes.ResourceExhausted)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	s := NewFromConfig(nil, ServerConfig{Addr: ":9000"})
+	if s.addr != ":9000" {
+		t.Errorf("unexpected address; expected %s, got %s", ":9000", s.addr)
+	}
+	if s.srv == nil {
+		t.Error("expected grpc server to be set")
+	}
+}
+
+func TestNewFromConfig_Defaults(t *testing.T) {
+	s := NewFromConfig(nil, ServerConfig{})
+	if s.addr != ":5000" {
+		t.Errorf("unexpected address; expected %s, got %s", ":5000", s.addr)
+	}
+	s = NewFromConfig(nil, ServerConfig{Addr: ":9000"}, WithAddress(":9001"))
+	if s.addr != ":9001" {
+		t.Errorf("unexpected address; expected %s, got %s", ":9001", s.addr)
+	}
+}
+
+func TestClientConfig_DialOptions(t *testing.T) {
+	if opts := (ClientConfig{}).DialOptions(); opts != nil {
+		t.Errorf("unexpected options for zero config; got %d, want none", len(opts))
+	}
+	opts := ClientConfig{MaxRecvMsgSize: 1, MaxSendMsgSize: 1}.DialOptions()
+	if len(opts) != 1 {
+		t.Errorf("unexpected options; got %d, want 1", len(opts))
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	addr, stop := serve(t)
+	defer stop()
+	// zero dial timeout uses the default
+	cc, err := NewClientFromConfig(ClientConfig{Target: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if err := check(t, cc); err != nil {
+		t.Error(err)
+	}
+	// the health response is larger than the configured receive size
+	cc, err = NewClientFromConfig(ClientConfig{Target: addr, MaxRecvMsgSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if code := status.Code(check(t, cc)); code != codes.ResourceExhausted {
+		t.Errorf("unexpected status code; got %v, want %v", code, codes.ResourceExhausted)
+	}
+}
+
+func TestNewClientFromConfig_DialTimeout(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	start := time.Now()
+	_, err = NewClientFromConfig(ClientConfig{Target: addr, DialTimeout: time.Millisecond * 100})
+	if err == nil {
+		t.Fatal("expected dial error")
+	}
+	if elapsed := time.Since(start); elapsed > defaultDialTimeout {
+		t.Errorf("dial did not use the configured timeout; took %v", elapsed)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/rs/xid"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
@@ -53,3 +54,42 @@ func HealthRequest(host string, svc []string, reqIDField string, log zerolog.Log
 	err = <-errC
 	return err
 }
+
+// A HealthChecker checks the health of a service on a gRPC server with the
+// standard health checking protocol. It implements the Checker interface of
+// the kit http package so a server's readiness can depend on a gRPC service.
+//
+// Example:
+//
+//	srv := http.New(http.WithCheck(http.Check{
+//		Name:     "content",
+//		Checker:  grpc.NewHealthChecker(cc, "kit.content.v1.ContentManager"),
+//		Critical: true,
+//	}))
+type HealthChecker struct {
+	client  healthpb.HealthClient
+	service string
+}
+
+// NewHealthChecker constructs a HealthChecker for a service on the server
+// of a client connection, an empty service checks the server as a whole
+func NewHealthChecker(cc *grpc.ClientConn, service string) *HealthChecker {
+	return &HealthChecker{
+		client:  healthpb.NewHealthClient(cc),
+		service: service,
+	}
+}
+
+// Check returns an error unless the service is serving
+func (c *HealthChecker) Check(ctx context.Context) error {
+	rsp, err := c.client.Check(ctx, &healthpb.HealthCheckRequest{
+		Service: c.service,
+	})
+	if err != nil {
+		return err
+	}
+	if rsp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%s: %s", c.service, rsp.GetStatus())
+	}
+	return nil
+}
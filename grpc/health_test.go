@@ -1,6 +1,7 @@
 package grpc_test
 
 import (
+	"context"
 	"errors"
 	"net"
 	"os"
@@ -56,3 +57,33 @@ func TestHealthRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthChecker(t *testing.T) {
+	listener, err := net.Listen("tcp", ":50001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	srv := grpc.NewServer()
+	defer srv.Stop()
+	hs := health.NewServer()
+	hs.SetServingStatus("test", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("down", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	cc, err := grpckit.NewClient(":50001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	err = grpckit.NewHealthChecker(cc, "test").Check(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+	err = grpckit.NewHealthChecker(cc, "down").Check(context.Background())
+	if err == nil || err.Error() != "down: NOT_SERVING" {
+		t.Errorf("unexpected err; expected %v, got %v", "down: NOT_SERVING", err)
+	}
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultCheckTimeout bounds a check without a configured timeout
+const defaultCheckTimeout = time.Second * 5
+
+// A Checker checks a dependency of a server, e.g. a database connection,
+// returning an error if it is unavailable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a func to a Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// SQLChecker returns a Checker pinging a database
+func SQLChecker(db *sql.DB) Checker {
+	return CheckerFunc(db.PingContext)
+}
+
+// A Check registers a Checker with a server
+type Check struct {
+	Name    string
+	Checker Checker
+	// Timeout bounds each run of the check, defaults to 5 seconds
+	Timeout time.Duration
+	// Critical checks fail readiness, other failing checks are reported
+	// as degraded
+	Critical bool
+	// Liveness checks are also run by the liveness endpoint, they should
+	// only fail when the server must be restarted
+	Liveness bool
+}
+
+// Check statuses reported by the liveness and readiness endpoints
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFailing  = "failing"
+)
+
+// A CheckResult reports the result of a check
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// A CheckReport reports the results of the checks run by a liveness or
+// readiness request
+type CheckReport struct {
+	Status  string        `json:"status"`
+	Serving bool          `json:"serving"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+// WithCheck returns an Option to register a check with the server
+func WithCheck(c Check) Option {
+	return func(s *Server) {
+		s.RegisterCheck(c)
+	}
+}
+
+// WithCheckCacheTTL returns an Option to configure how long check results
+// are cached, so frequent probes do not overload dependencies. The default
+// is 1 second.
+func WithCheckCacheTTL(d time.Duration) Option {
+	return func(s *Server) {
+		s.checks.ttl = d
+	}
+}
+
+// RegisterCheck registers a check run by the liveness and readiness
+// endpoints, checks may be registered once the server has started e.g.
+// when a connection is established
+func (s *Server) RegisterCheck(c Check) {
+	if c.Timeout == 0 {
+		c.Timeout = defaultCheckTimeout
+	}
+	s.checks.mu.Lock()
+	defer s.checks.mu.Unlock()
+	s.checks.checks = append(s.checks.checks, &check{Check: c})
+}
+
+// checkRegistry holds the checks registered with a server
+type checkRegistry struct {
+	ttl    time.Duration
+	mu     sync.Mutex // protects checks
+	checks []*check
+}
+
+// A check is a registered Check and its cached result
+type check struct {
+	Check
+	mu     sync.Mutex // protects result, held while the check runs
+	result *CheckResult
+}
+
+// run returns the cached result of the check, running it if the cached
+// result is older than ttl
+func (c *check) run(ctx context.Context, ttl time.Duration) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.result != nil && time.Since(c.result.CheckedAt) < ttl {
+		return *c.result
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	start := time.Now()
+	res := CheckResult{
+		Name:      c.Name,
+		Status:    StatusOK,
+		Critical:  c.Critical,
+		CheckedAt: start,
+	}
+	errC := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errC <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		errC <- c.Checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errC:
+	case <-ctx.Done():
+		// checkers ignoring ctx are not waited for
+		err = ctx.Err()
+	}
+	res.Duration = time.Since(start).String()
+	if err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+	}
+	c.result = &res
+	return res
+}
+
+// run runs the checks concurrently, only liveness checks if liveness is
+// true, returning a report
+func (r *checkRegistry) run(ctx context.Context, liveness bool) CheckReport {
+	r.mu.Lock()
+	var checks []*check
+	for _, c := range r.checks {
+		if !liveness || c.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.Unlock()
+	report := CheckReport{
+		Status: StatusOK,
+		Checks: make([]CheckResult, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, r.ttl)
+		}(i, c)
+	}
+	wg.Wait()
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+	for _, res := range report.Checks {
+		switch {
+		case res.Status == StatusOK:
+		case res.Critical:
+			report.Status = StatusFailing
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// Liveness returns a handler for liveness probes running the liveness
+// checks. It responds 200 OK unless a critical liveness check fails.
+func (s *Server) Liveness() http.Handler {
+	return s.checkHandler(true)
+}
+
+// Readiness returns a handler for readiness probes running every check. It
+// responds 503 Service Unavailable if a critical check fails or the server
+// is not serving, failing non-critical checks are reported as degraded.
+func (s *Server) Readiness() http.Handler {
+	return s.checkHandler(false)
+}
+
+func (s *Server) checkHandler(liveness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// results are cached and shared between requests so checks do not
+		// run with the request context
+		report := s.checks.run(context.Background(), liveness)
+		report.Serving = s.serving()
+		status := http.StatusOK
+		if report.Status == StatusFailing || (!liveness && !report.Serving) {
+			status = http.StatusServiceUnavailable
+		}
+		b, _ := json.Marshal(report)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, err := w.Write(b)
+		if err != nil {
+			s.log.Error().Err(err).Msg("error writing to response")
+		}
+	})
+}
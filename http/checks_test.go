@@ -0,0 +1,146 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	h "go.soon.build/kit/http"
+)
+
+func TestServer_Checks(t *testing.T) {
+	ok := h.CheckerFunc(func(ctx context.Context) error {
+		return nil
+	})
+	failing := h.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	slow := h.CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	tc := map[string]struct {
+		checks   []h.Check
+		running  bool
+		path     string
+		xCode    int
+		xStatus  string
+		xResults map[string]string
+	}{
+		"ready": {
+			checks: []h.Check{
+				{Name: "db", Checker: ok, Critical: true},
+			},
+			running:  true,
+			path:     "/readyz",
+			xCode:    http.StatusOK,
+			xStatus:  h.StatusOK,
+			xResults: map[string]string{"db": h.StatusOK},
+		},
+		"not serving": {
+			checks: []h.Check{
+				{Name: "db", Checker: ok, Critical: true},
+			},
+			path:     "/readyz",
+			xCode:    http.StatusServiceUnavailable,
+			xStatus:  h.StatusOK,
+			xResults: map[string]string{"db": h.StatusOK},
+		},
+		"critical failing": {
+			checks: []h.Check{
+				{Name: "db", Checker: failing, Critical: true},
+				{Name: "cache", Checker: ok},
+			},
+			running:  true,
+			path:     "/readyz",
+			xCode:    http.StatusServiceUnavailable,
+			xStatus:  h.StatusFailing,
+			xResults: map[string]string{"db": h.StatusFailing, "cache": h.StatusOK},
+		},
+		"degraded": {
+			checks: []h.Check{
+				{Name: "db", Checker: ok, Critical: true},
+				{Name: "cache", Checker: slow, Timeout: time.Millisecond * 10},
+			},
+			running:  true,
+			path:     "/readyz",
+			xCode:    http.StatusOK,
+			xStatus:  h.StatusDegraded,
+			xResults: map[string]string{"db": h.StatusOK, "cache": h.StatusFailing},
+		},
+		"liveness": {
+			checks: []h.Check{
+				{Name: "db", Checker: failing, Critical: true},
+				{Name: "deadlock", Checker: ok, Critical: true, Liveness: true},
+			},
+			path:     "/livez",
+			xCode:    http.StatusOK,
+			xStatus:  h.StatusOK,
+			xResults: map[string]string{"deadlock": h.StatusOK},
+		},
+	}
+	for name, tt := range tc {
+		t.Run(name, func(t *testing.T) {
+			opts := []h.Option{h.WithAddr("127.0.0.1:0"), h.WithHealth(h.HealthOptions{
+				LivenessPath:  "/livez",
+				ReadinessPath: "/readyz",
+			})}
+			for _, c := range tt.checks {
+				opts = append(opts, h.WithCheck(c))
+			}
+			s := h.New(opts...)
+			if tt.running {
+				stop := serve(t, s)
+				defer stop()
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			s.Srv.Handler.ServeHTTP(w, r)
+			if w.Code != tt.xCode {
+				t.Errorf("unexpected status code; got %v, want %v", w.Code, tt.xCode)
+			}
+			var report h.CheckReport
+			err := json.Unmarshal(w.Body.Bytes(), &report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.xStatus {
+				t.Errorf("unexpected status; got %v, want %v", report.Status, tt.xStatus)
+			}
+			results := map[string]string{}
+			for _, res := range report.Checks {
+				results[res.Name] = res.Status
+			}
+			if len(results) != len(tt.xResults) {
+				t.Errorf("unexpected checks; got %v, want %v", results, tt.xResults)
+			}
+			for name, status := range tt.xResults {
+				if results[name] != status {
+					t.Errorf("unexpected %s check status; got %v, want %v", name, results[name], status)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ChecksCached(t *testing.T) {
+	var calls int32
+	counter := h.CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	s := h.New(h.WithCheckCacheTTL(time.Minute))
+	s.RegisterCheck(h.Check{Name: "counter", Checker: counter})
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		s.Readiness().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("unexpected check calls; got %d, want %d", n, 1)
+	}
+}
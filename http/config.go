@@ -0,0 +1,75 @@
+package http
+
+import (
+	"time"
+)
+
+// Config holds server settings which can be loaded with the kit config
+// package as part of a service configuration
+//
+// Example:
+//
+//	type Config struct {
+//		HTTP http.Config `mapstructure:"http"`
+//	}
+//	...
+//	srv := http.NewFromConfig(c.HTTP, http.WithHandler(router))
+type Config struct {
+	Addr         string        `mapstructure:"addr" default:":5000" validate:"hostport" desc:"server listen address"`
+	Socket       string        `mapstructure:"socket" desc:"unix socket path to listen on instead of addr"`
+	StopTimeout  time.Duration `mapstructure:"stopTimeout" default:"10s" desc:"time to wait for connections to terminate on shutdown"`
+	PreStopDelay time.Duration `mapstructure:"preStopDelay" desc:"time to report not ready before stopping, so load balancers stop routing requests"`
+	ReadTimeout  time.Duration `mapstructure:"readTimeout" desc:"maximum duration for reading a request, 0 for no timeout"`
+	WriteTimeout time.Duration `mapstructure:"writeTimeout" desc:"maximum duration for writing a response, 0 for no timeout"`
+	IdleTimeout  time.Duration `mapstructure:"idleTimeout" desc:"maximum time to wait for the next request on a keep-alive connection"`
+	Health       HealthConfig  `mapstructure:"health"`
+}
+
+// HealthConfig holds healthcheck endpoint settings
+type HealthConfig struct {
+	Path    string `mapstructure:"path" desc:"healthcheck endpoint path, disabled when empty"`
+	AppName string `mapstructure:"appName" desc:"app name reported by the healthcheck"`
+	Version string `mapstructure:"version" desc:"app version reported by the healthcheck"`
+	// LivenessPath and ReadinessPath serve the liveness and readiness
+	// checks, disabled when empty
+	LivenessPath  string `mapstructure:"livenessPath" desc:"liveness probe endpoint path"`
+	ReadinessPath string `mapstructure:"readinessPath" desc:"readiness probe endpoint path"`
+}
+
+// Options returns the Options configuring a server from c, zero values
+// keep the server defaults
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Addr != "" {
+		opts = append(opts, WithAddr(c.Addr))
+	}
+	if c.Socket != "" {
+		opts = append(opts, WithUnixSocket(c.Socket))
+	}
+	if c.StopTimeout != 0 {
+		opts = append(opts, WithStopTimeout(c.StopTimeout))
+	}
+	if c.PreStopDelay != 0 {
+		opts = append(opts, WithPreStopDelay(c.PreStopDelay))
+	}
+	if h := c.Health; h.Path != "" || h.LivenessPath != "" || h.ReadinessPath != "" {
+		opts = append(opts, WithHealth(HealthOptions{
+			Path:          h.Path,
+			AppName:       h.AppName,
+			Version:       h.Version,
+			LivenessPath:  h.LivenessPath,
+			ReadinessPath: h.ReadinessPath,
+		}))
+	}
+	return append(opts, func(s *Server) {
+		s.Srv.ReadTimeout = c.ReadTimeout
+		s.Srv.WriteTimeout = c.WriteTimeout
+		s.Srv.IdleTimeout = c.IdleTimeout
+	})
+}
+
+// NewFromConfig constructs a server from c, opts are applied after the
+// configured settings so they take precedence
+func NewFromConfig(c Config, opts ...Option) *Server {
+	return New(append(c.Options(), opts...)...)
+}
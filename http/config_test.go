@@ -0,0 +1,40 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	h "go.soon.build/kit/http"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	s := h.NewFromConfig(h.Config{
+		Addr:        ":9000",
+		ReadTimeout: time.Second,
+		Health:      h.HealthConfig{Path: "/healthz"},
+	}, h.WithHandler(handler))
+	if s.Srv.Addr != ":9000" {
+		t.Errorf("unexpected address; expected %s, got %s", ":9000", s.Srv.Addr)
+	}
+	if s.Srv.ReadTimeout != time.Second {
+		t.Errorf("unexpected read timeout; expected %v, got %v", time.Second, s.Srv.ReadTimeout)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	s.Srv.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status code; got %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestNewFromConfig_Defaults(t *testing.T) {
+	s := h.NewFromConfig(h.Config{}, h.WithAddr(":9001"))
+	if s.Srv.Addr != ":9001" {
+		t.Errorf("unexpected address; expected %s, got %s", ":9001", s.Srv.Addr)
+	}
+}
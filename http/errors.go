@@ -9,9 +9,47 @@ import (
 )
 
 type errResponse struct {
-	Message string `json:"message"`
-	Code    int    `json:"code"`
-	ErrID   string `json:"errID"`
+	Message string      `json:"message"`
+	Code    int         `json:"code"`
+	ErrID   string      `json:"errID"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Error is an error with an HTTP status and a message safe to return to
+// clients. The cause is logged but not included in the response.
+// Handlers adapted by ErrHandler may return an Error, or an error wrapping
+// one, to control the error response.
+//
+// Example:
+//
+//	return &http.Error{Status: http.StatusNotFound, Message: "user not found", Err: err}
+type Error struct {
+	Status  int
+	Message string
+	Err     error
+	// Details are included in the response, e.g. validation failures
+	Details interface{}
+}
+
+// NewError returns an Error with status, public message msg and cause err
+func NewError(status int, msg string, err error) *Error {
+	return &Error{Status: status, Message: msg, Err: err}
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Err == nil {
+		return msg
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of e
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 // ErrBadRequest writes a bad request err response
@@ -43,7 +81,12 @@ func NewErr(status int, msg string) errResponse {
 
 // RequestErr handles logs an error and writes an error response
 func RequestErr(status int, log zerolog.Logger, w http.ResponseWriter, err error, msg string) {
-	res := NewErr(status, msg)
+	writeErr(NewErr(status, msg), log, w, err)
+}
+
+// writeErr logs err and writes res
+func writeErr(res errResponse, log zerolog.Logger, w http.ResponseWriter, err error) {
+	status, msg := res.Code, res.Message
 	var lvl zerolog.Level
 	if status >= 500 {
 		lvl = zerolog.ErrorLevel
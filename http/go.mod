@@ -1,6 +1,6 @@
 module go.soon.build/kit/http
 
-go 1.12
+go 1.13
 
 require (
 	github.com/rs/xid v1.2.1
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrHandlerFunc is an http.HandlerFunc returning an error, adapted to an
+// http.Handler by ErrHandler
+//
+// Example:
+//
+//	func (h *handlers) getUser(w http.ResponseWriter, r *http.Request) error {
+//		u, err := h.store.User(r.Context(), id)
+//		if err != nil {
+//			return err
+//		}
+//		return json.NewEncoder(w).Encode(u)
+//	}
+type ErrHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// An ErrMapper maps an error returned by a handler to an Error, returning
+// nil if it does not map the error. Mappers let domain errors be mapped to
+// statuses in one place.
+//
+// Example:
+//
+//	func mapErr(err error) *http.Error {
+//		if errors.Is(err, store.ErrNotFound) {
+//			return http.NewError(http.StatusNotFound, "not found", err)
+//		}
+//		return nil
+//	}
+type ErrMapper func(err error) *Error
+
+// WithErrMapper returns an Option to add an ErrMapper used by handlers
+// adapted with the server ErrHandler method
+func WithErrMapper(m ErrMapper) Option {
+	return func(s *Server) {
+		s.errMappers = append(s.errMappers, m)
+	}
+}
+
+// ErrHandler adapts fn to an http.Handler using the server logger and
+// error mappers
+func (s *Server) ErrHandler(fn ErrHandlerFunc) http.Handler {
+	return ErrHandler(s.log, fn, s.errMappers...)
+}
+
+// ErrHandler adapts fn to an http.Handler writing an error response as
+// RequestErr if fn returns an error. The first mapper returning an Error
+// for the error sets the response, else an Error in the error chain. Other
+// errors are internal server errors, their message is not returned to the
+// client. A mapped Error without a status is an internal server error.
+//
+// If fn had already written the response headers the error is only logged.
+func ErrHandler(log zerolog.Logger, fn ErrHandlerFunc, mappers ...ErrMapper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hw := &headerWriter{ResponseWriter: w}
+		err := fn(hw, r)
+		if err == nil {
+			return
+		}
+		if hw.wroteHeader {
+			log.Error().Err(err).Msg("error after response headers were written")
+			return
+		}
+		e := errFor(err, mappers)
+		msg := e.Message
+		if msg == "" {
+			msg = http.StatusText(e.Status)
+		}
+		res := NewErr(e.Status, msg)
+		res.Details = e.Details
+		writeErr(res, log, w, err)
+	})
+}
+
+// errFor returns the Error describing the response to err
+func errFor(err error, mappers []ErrMapper) *Error {
+	for _, m := range mappers {
+		if e := m(err); e != nil {
+			if e.Status == 0 {
+				mapped := *e
+				mapped.Status = http.StatusInternalServerError
+				return &mapped
+			}
+			return e
+		}
+	}
+	var e *Error
+	if errors.As(err, &e) && e.Status != 0 {
+		return e
+	}
+	return &Error{Status: http.StatusInternalServerError}
+}
@@ -0,0 +1,145 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	h "go.soon.build/kit/http"
+)
+
+var (
+	errNotFound = errors.New("not found")
+	errNoStatus = errors.New("no status")
+)
+
+func TestErrHandler(t *testing.T) {
+	mapErr := func(err error) *h.Error {
+		if errors.Is(err, errNotFound) {
+			return h.NewError(http.StatusNotFound, "user not found", err)
+		}
+		if errors.Is(err, errNoStatus) {
+			return &h.Error{Message: "mapped without status", Err: err}
+		}
+		return nil
+	}
+	tc := map[string]struct {
+		err      error
+		xCode    int
+		xMessage string
+		xDetails interface{}
+		xLog     string
+	}{
+		"typed error": {
+			err:      h.NewError(http.StatusBadRequest, "invalid user id", errors.New("strconv: invalid syntax")),
+			xCode:    http.StatusBadRequest,
+			xMessage: "invalid user id",
+			xLog:     "invalid user id: strconv: invalid syntax",
+		},
+		"wrapped typed error": {
+			err:      fmt.Errorf("get user: %w", &h.Error{Status: http.StatusConflict}),
+			xCode:    http.StatusConflict,
+			xMessage: "Conflict",
+			xLog:     "get user: Conflict",
+		},
+		"details": {
+			err: &h.Error{
+				Status:  http.StatusUnprocessableEntity,
+				Message: "invalid user",
+				Details: map[string]interface{}{"name": "required"},
+			},
+			xCode:    http.StatusUnprocessableEntity,
+			xMessage: "invalid user",
+			xDetails: map[string]interface{}{"name": "required"},
+			xLog:     "invalid user",
+		},
+		"mapped error": {
+			err:      fmt.Errorf("get user: %w", errNotFound),
+			xCode:    http.StatusNotFound,
+			xMessage: "user not found",
+			xLog:     "get user: not found",
+		},
+		"mapped error without status": {
+			err:      errNoStatus,
+			xCode:    http.StatusInternalServerError,
+			xMessage: "mapped without status",
+			xLog:     "no status",
+		},
+		"unknown error": {
+			err:      errors.New("connection refused"),
+			xCode:    http.StatusInternalServerError,
+			xMessage: "Internal Server Error",
+			xLog:     "connection refused",
+		},
+	}
+	for name, tt := range tc {
+		t.Run(name, func(t *testing.T) {
+			logWriter := bytes.Buffer{}
+			s := h.New(h.WithLogger(zerolog.New(&logWriter)), h.WithErrMapper(mapErr))
+			handler := s.ErrHandler(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+
+			if w.Code != tt.xCode {
+				t.Errorf("unexpected response status; expected %d, got %d", tt.xCode, w.Code)
+			}
+			var body map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body["message"] != tt.xMessage {
+				t.Errorf("unexpected message; expected %s, got %v", tt.xMessage, body["message"])
+			}
+			if fmt.Sprint(body["details"]) != fmt.Sprint(tt.xDetails) {
+				t.Errorf("unexpected details; expected %v, got %v", tt.xDetails, body["details"])
+			}
+			entries := logEntriesFromBuffer(logWriter)
+			if len(entries) != 1 {
+				t.Fatalf("unexpected log entries; expected %d entries, got %d", 1, len(entries))
+			}
+			if entries[0]["error"] != tt.xLog {
+				t.Errorf("unexpected log field; expected %s, got %v", tt.xLog, entries[0]["error"])
+			}
+			if entries[0]["errID"] != body["errID"] {
+				t.Errorf("unexpected log field; expected %v, got %v", body["errID"], entries[0]["errID"])
+			}
+		})
+	}
+}
+
+func TestErrHandler_HeadersWritten(t *testing.T) {
+	logWriter := bytes.Buffer{}
+	handler := h.ErrHandler(zerolog.New(&logWriter), func(w http.ResponseWriter, r *http.Request) error {
+		_, _ = io.WriteString(w, "partial")
+		return errors.New("write failed")
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+	if w.Body.String() != "partial" {
+		t.Errorf("unexpected body; expected %s, got %s", "partial", w.Body.String())
+	}
+	entries := logEntriesFromBuffer(logWriter)
+	if len(entries) != 1 || entries[0]["error"] != "write failed" {
+		t.Errorf("unexpected log entries; got %v", entries)
+	}
+}
+
+func TestError_Unwrap(t *testing.T) {
+	err := fmt.Errorf("get user: %w", h.NewError(http.StatusNotFound, "user not found", errNotFound))
+	if !errors.Is(err, errNotFound) {
+		t.Error("expected error to wrap cause")
+	}
+	var e *h.Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Errorf("expected error to wrap an Error; got %v", e)
+	}
+}
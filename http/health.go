@@ -7,10 +7,15 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// HealthOptions configures the healthcheck endpoints of a server
 type HealthOptions struct {
 	Path    string
 	AppName string
 	Version string
+	// LivenessPath and ReadinessPath serve the Liveness and Readiness
+	// handlers when set, e.g. `/livez` and `/readyz`
+	LivenessPath  string
+	ReadinessPath string
 }
 
 // Health returns a handler for healthcheck requests
@@ -28,10 +33,10 @@ func (s *Server) Health(h HealthOptions) http.Handler {
 		b, _ := json.Marshal(healthResponse{
 			App:     h.AppName,
 			Version: h.Version,
-			Serving: s.Running,
+			Serving: s.serving(),
 		})
 		w.Header().Set("Content-Type", "application/json")
-		if s.Running {
+		if s.serving() {
 			w.WriteHeader(http.StatusOK)
 		} else {
 			w.WriteHeader(http.StatusServiceUnavailable)
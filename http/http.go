@@ -4,10 +4,11 @@ package http
 
 import (
 	"context"
-	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -25,12 +26,28 @@ import (
 // 		// handle server close err
 // 	}
 type Server struct {
-	Srv         *http.Server
-	Running     bool
-	log         zerolog.Logger
-	stopTimeout time.Duration
-	handler     http.Handler
-	healthOpt   HealthOptions
+	Srv *http.Server
+	// Running is a best-effort mirror of the server state, true while the
+	// server is serving requests. It is written without synchronisation by
+	// the goroutines starting and stopping the server, setting it has no
+	// effect.
+	//
+	// Deprecated: use State, which is safe for concurrent use.
+	Running      bool
+	state        int32 // State, accessed atomically
+	inFlight     int64 // requests being handled, accessed atomically
+	log          zerolog.Logger
+	stopTimeout  time.Duration
+	preStopDelay time.Duration
+	handler      http.Handler
+	healthOpt    HealthOptions
+	tls          *certReloader
+	checks       checkRegistry
+	errMappers   []ErrMapper
+	listener     net.Listener
+	socket       string
+	ready        chan struct{}
+	onReady      func(net.Addr)
 }
 
 // New constructs a server
@@ -39,6 +56,8 @@ func New(opts ...Option) *Server {
 		Srv:         &http.Server{Addr: ":5000"},
 		log:         zerolog.New(os.Stdout),
 		stopTimeout: time.Second * 10,
+		checks:      checkRegistry{ttl: time.Second},
+		ready:       make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -47,12 +66,27 @@ func New(opts ...Option) *Server {
 	if s.handler == nil {
 		s.Srv.Handler = s.Health(HealthOptions{AppName: "kit"})
 	}
-	if s.healthOpt.Path != "" {
+	if h := s.healthOpt; h.Path != "" || h.LivenessPath != "" || h.ReadinessPath != "" {
 		mux := http.NewServeMux()
-		mux.Handle("/", s.handler)
-		mux.Handle(s.healthOpt.Path, s.Health(s.healthOpt))
+		mux.Handle("/", s.Srv.Handler)
+		if h.Path != "" {
+			mux.Handle(h.Path, s.Health(h))
+		}
+		if h.LivenessPath != "" {
+			mux.Handle(h.LivenessPath, s.Liveness())
+		}
+		if h.ReadinessPath != "" {
+			mux.Handle(h.ReadinessPath, s.Readiness())
+		}
 		s.Srv.Handler = mux
 	}
+	if s.tls != nil {
+		s.Srv.TLSConfig = s.tls.config(s.Srv.TLSConfig)
+		if s.tls.caFile != "" {
+			s.Srv.Handler = clientIdentityHandler(s.Srv.Handler)
+		}
+	}
+	s.Srv.Handler = s.inFlightHandler(s.Srv.Handler)
 	return s
 }
 
@@ -97,18 +131,47 @@ func WithStopTimeout(d time.Duration) Option {
 
 // Start starts the server listening, will block on signal or error
 func (s *Server) Start(ctx context.Context) error {
+	if s.tls != nil {
+		// fail early on missing or invalid certificate files
+		err := s.tls.load()
+		if err != nil {
+			// the server owns a listener provided with WithListener
+			if s.listener != nil {
+				s.listener.Close()
+			}
+			s.setState(StateStopped)
+			return err
+		}
+	}
+	err := s.listen()
+	if err != nil {
+		s.setState(StateStopped)
+		return err
+	}
+	addr := s.listener.Addr()
 	errC := make(chan error, 1)
-	// listen
+	// serve
 	go func() {
-		s.log.Debug().Msg(fmt.Sprintf("listening on %s", s.Srv.Addr))
-		s.Running = true
-		err := s.Srv.ListenAndServe()
+		s.log.Debug().Str("addr", addr.String()).Msg("listening")
+		s.transition(StateStarting, StateServing)
+		close(s.ready)
+		if s.onReady != nil {
+			s.onReady(addr)
+		}
+		var err error
+		if s.tls != nil {
+			// certificates are provided by the TLS config
+			err = s.Srv.ServeTLS(s.listener, "", "")
+		} else {
+			err = s.Srv.Serve(s.listener)
+		}
 		switch err {
 		case http.ErrServerClosed:
-			s.Running = false
-			s.log.Debug().Err(err).Bool("running", s.Running).Msg("server closed")
+			// in-flight requests are drained by Stop
+			s.log.Debug().Err(err).Msg("server closed")
 		case nil:
 		default:
+			s.setState(StateStopped)
 			errC <- err
 		}
 		close(errC)
@@ -123,15 +186,47 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 }
 
-// Stop stops the running server
+// Stop gracefully stops the running server. The server first drains,
+// reporting not ready for the pre-stop delay while still serving requests,
+// then stops accepting connections and waits up to the stop timeout for
+// in-flight requests to complete. Requests still in-flight are then cut off
+// by closing their connections.
 func (s *Server) Stop() error {
-	if s.Srv != nil {
-		s.log.Debug().Msg("gracefully stopping server")
-		ctx, cancel := context.WithTimeout(context.Background(), s.stopTimeout)
-		defer cancel()
-		return s.Srv.Shutdown(ctx)
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown gracefully stops the running server as Stop. If ctx is done
+// before the server has stopped, the pre-stop delay ends early and requests
+// still in-flight are cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.Srv == nil {
+		return nil
+	}
+	if !s.transition(StateServing, StateDraining) && !s.transition(StateStarting, StateDraining) {
+		// already stopping or stopped
+		return nil
+	}
+	defer s.setState(StateStopped)
+	if s.preStopDelay > 0 {
+		s.log.Debug().Dur("delay", s.preStopDelay).Msg("draining server before stopping")
+		t := time.NewTimer(s.preStopDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			s.log.Debug().Msg("drain interrupted")
+		}
+	}
+	s.log.Debug().Msg("gracefully stopping server")
+	ctx, cancel := context.WithTimeout(ctx, s.stopTimeout)
+	defer cancel()
+	err := s.Srv.Shutdown(ctx)
+	if err != context.DeadlineExceeded && err != context.Canceled {
+		return err
 	}
-	return nil
+	n := atomic.LoadInt64(&s.inFlight)
+	s.log.Warn().Int64("requests", n).Msg("stop timeout exceeded, closing connections of in-flight requests")
+	return s.Srv.Close()
 }
 
 // CtxWithSignal returns a context that completes when one of the
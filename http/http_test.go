@@ -25,7 +25,7 @@ func TestServer_StartStop(t *testing.T) {
 		stopped <- true
 	}()
 	time.Sleep(time.Second)
-	if !s.Running {
+	if s.State() != h.StateServing {
 		t.Errorf("server state is not running")
 	}
 	// test request
@@ -51,7 +51,7 @@ func TestServer_StartStop(t *testing.T) {
 		t.Error(err)
 	}
 	time.Sleep(time.Second)
-	if s.Running {
+	if s.State() == h.StateServing {
 		t.Errorf("server state is still running")
 	}
 }
@@ -84,12 +84,6 @@ func TestWithHealth(t *testing.T) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusAccepted)
 	})
-	s := h.New(h.WithHandler(handler), h.WithHealth(h.HealthOptions{
-		AppName: "test",
-		Version: "x",
-		Path:    "/healthz",
-	}))
-
 	tc := map[string]struct {
 		serving bool
 		path    string
@@ -111,9 +105,17 @@ func TestWithHealth(t *testing.T) {
 	}
 	for name, tt := range tc {
 		t.Run(name, func(t *testing.T) {
+			s := h.New(h.WithAddr("127.0.0.1:0"), h.WithHandler(handler), h.WithHealth(h.HealthOptions{
+				AppName: "test",
+				Version: "x",
+				Path:    "/healthz",
+			}))
+			if tt.serving {
+				stop := serve(t, s)
+				defer stop()
+			}
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
-			s.Running = tt.serving
 			s.Srv.Handler.ServeHTTP(w, r)
 			if w.Code != tt.xCode {
 				t.Errorf("unexpected status code; got %v, want %v", w.Code, tt.xCode)
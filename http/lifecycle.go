@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// State is the lifecycle state of a server
+type State int32
+
+// Server lifecycle states, a server moves through them in order
+const (
+	// StateStarting servers have not started listening yet
+	StateStarting State = iota
+	// StateServing servers are accepting connections and report ready
+	StateServing
+	// StateDraining servers report not ready so load balancers stop
+	// routing to them, in-flight requests are completed
+	StateDraining
+	// StateStopped servers have shut down
+	StateStopped
+)
+
+func (st State) String() string {
+	switch st {
+	case StateStarting:
+		return "starting"
+	case StateServing:
+		return "serving"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// WithPreStopDelay returns an Option to configure how long a stopping
+// server keeps accepting requests while reporting not ready, so load
+// balancers stop routing requests to it before it stops listening
+func WithPreStopDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.preStopDelay = d
+	}
+}
+
+// State returns the lifecycle state of the server
+func (s *Server) State() State {
+	return State(atomic.LoadInt32(&s.state))
+}
+
+// serving returns true if the server is serving requests and has not
+// started draining
+func (s *Server) serving() bool {
+	return s.State() == StateServing
+}
+
+func (s *Server) setState(st State) {
+	atomic.StoreInt32(&s.state, int32(st))
+	// best-effort mirror for callers of the deprecated field
+	s.Running = st == StateServing
+	s.log.Debug().Str("state", st.String()).Msg("server state changed")
+}
+
+// transition moves the server from one state to another, returning false
+// if the server was not in the from state
+func (s *Server) transition(from, to State) bool {
+	if !atomic.CompareAndSwapInt32(&s.state, int32(from), int32(to)) {
+		return false
+	}
+	s.Running = to == StateServing
+	s.log.Debug().Str("state", to.String()).Msg("server state changed")
+	return true
+}
+
+// inFlightHandler counts the requests being handled by the server
+func (s *Server) inFlightHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,166 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	h "go.soon.build/kit/http"
+)
+
+// waitState waits for s to move to state st
+func waitState(t *testing.T, s *h.Server, st h.State) {
+	deadline := time.Now().Add(time.Second * 5)
+	for s.State() != st {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for state %v, got %v", st, s.State())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServer_StopDrains(t *testing.T) {
+	started, release, handled := make(chan bool), make(chan bool), make(chan bool, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+			handled <- true
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	s := h.New(
+		h.WithAddr("127.0.0.1:0"),
+		h.WithHandler(handler),
+		h.WithLogger(zerolog.Nop()),
+		h.WithHealth(h.HealthOptions{ReadinessPath: "/readyz"}),
+		// interrupted by the shutdown context
+		h.WithPreStopDelay(time.Minute),
+	)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.Start(context.Background())
+	}()
+	<-s.Ready()
+	if s.State() != h.StateServing || !s.Running {
+		t.Fatalf("unexpected state; expected %v, got %v", h.StateServing, s.State())
+	}
+	url := "http://" + s.Addr().String()
+	// in-flight request
+	go func() {
+		rsp, err := http.Get(url + "/slow")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		rsp.Body.Close()
+	}()
+	<-started
+
+	ctx, interrupt := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.Shutdown(ctx)
+	}()
+	waitState(t, s, h.StateDraining)
+	// readiness fails while draining, requests are still served
+	for path, code := range map[string]int{"/readyz": http.StatusServiceUnavailable, "/": http.StatusOK} {
+		rsp, err := http.Get(url + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode != code {
+			t.Errorf("unexpected status code for %s; got %v, want %v", path, rsp.StatusCode, code)
+		}
+	}
+	close(release)
+	<-handled
+	interrupt()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for the pre-stop delay to be interrupted")
+	}
+	err := <-errC
+	if err != nil {
+		t.Error(err)
+	}
+	if s.State() != h.StateStopped || s.Running {
+		t.Errorf("unexpected state; expected %v, got %v", h.StateStopped, s.State())
+	}
+}
+
+func TestServer_StopTimeout(t *testing.T) {
+	started, block := make(chan bool), make(chan bool)
+	defer close(block)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-block
+	})
+	buf := &syncBuffer{}
+	s := h.New(
+		h.WithAddr("127.0.0.1:0"),
+		h.WithHandler(handler),
+		h.WithLogger(zerolog.New(buf)),
+		h.WithStopTimeout(time.Millisecond*100),
+	)
+	go func() {
+		_ = s.Start(context.Background())
+	}()
+	<-s.Ready()
+	go func() {
+		rsp, err := http.Get("http://" + s.Addr().String())
+		if err == nil {
+			rsp.Body.Close()
+		}
+	}()
+	<-started
+	err := s.Stop()
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(buf.String(), `"requests":1`) {
+		t.Errorf("expected cut off requests to be logged, got %s", buf.String())
+	}
+}
+
+func TestState_String(t *testing.T) {
+	w := httptest.NewRecorder()
+	s := h.New()
+	s.Readiness().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if s.State().String() != "starting" {
+		t.Errorf("unexpected state; expected %s, got %s", "starting", s.State())
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status code; got %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by a logger
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// WithListener returns an Option to serve on a listener opened by the
+// caller, e.g. a socket passed by systemd socket activation or opened with
+// SO_REUSEPORT. The server address is ignored and the listener is closed
+// when the server stops or fails to start.
+func WithListener(l net.Listener) Option {
+	return func(s *Server) {
+		s.listener = l
+	}
+}
+
+// WithUnixSocket returns an Option to serve on a Unix domain socket at
+// path instead of the server address. A stale socket file left by a
+// previous process is removed before listening.
+func WithUnixSocket(path string) Option {
+	return func(s *Server) {
+		s.socket = path
+	}
+}
+
+// WithOnReady returns an Option to configure a func called with the bound
+// address once the server is accepting connections
+func WithOnReady(fn func(addr net.Addr)) Option {
+	return func(s *Server) {
+		s.onReady = fn
+	}
+}
+
+// Ready returns a channel closed once the server is accepting connections
+//
+// Example:
+//
+//	srv := http.New(http.WithAddr("localhost:0"))
+//	go srv.Start(ctx)
+//	<-srv.Ready()
+//	url := "http://" + srv.Addr().String()
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr returns the address the server is bound to, e.g. the port chosen
+// for a ":0" address, or nil if the server is not accepting connections yet
+func (s *Server) Addr() net.Addr {
+	select {
+	case <-s.ready:
+		return s.listener.Addr()
+	default:
+		return nil
+	}
+}
+
+// listen opens the server listener, unless one was provided
+func (s *Server) listen() error {
+	if s.listener != nil {
+		return nil
+	}
+	network, addr := "tcp", s.Srv.Addr
+	if s.socket != "" {
+		network, addr = "unix", s.socket
+		fi, err := os.Stat(addr)
+		if err == nil && fi.Mode()&os.ModeSocket != 0 {
+			err = os.Remove(addr)
+			if err != nil {
+				return fmt.Errorf("error removing stale socket: %v", err)
+			}
+		}
+	}
+	if addr == "" {
+		addr = ":http"
+		if s.tls != nil {
+			addr = ":https"
+		}
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	return nil
+}
@@ -0,0 +1,159 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	h "go.soon.build/kit/http"
+)
+
+// serve starts s, returning a func to stop it
+func serve(t *testing.T, s *h.Server) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan bool)
+	go func() {
+		err := s.Start(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		close(stopped)
+	}()
+	select {
+	case <-s.Ready():
+	case <-stopped:
+		t.Fatal("server stopped before ready")
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for server ready")
+	}
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+func TestServer_Addr(t *testing.T) {
+	s := h.New(h.WithAddr("127.0.0.1:0"))
+	if s.Addr() != nil {
+		t.Errorf("unexpected address before start; got %v", s.Addr())
+	}
+	stop := serve(t, s)
+	defer stop()
+	addr := s.Addr().(*net.TCPAddr)
+	if addr.Port == 0 {
+		t.Fatal("expected a bound port")
+	}
+	rsp, err := http.Get("http://" + addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code; got %v, want %v", rsp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestWithListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan net.Addr, 1)
+	s := h.New(h.WithListener(l), h.WithOnReady(func(addr net.Addr) {
+		ready <- addr
+	}))
+	stop := serve(t, s)
+	if addr := <-ready; addr.String() != l.Addr().String() {
+		t.Errorf("unexpected address; expected %s, got %s", l.Addr(), addr)
+	}
+	rsp, err := http.Get("http://" + s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	stop()
+	// the listener is closed on stop
+	_, err = net.Dial("tcp", l.Addr().String())
+	if err == nil {
+		t.Error("expected listener to be closed")
+	}
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "http.sock")
+	// stale socket left by a previous process
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := h.New(h.WithUnixSocket(path))
+	stop := serve(t, s)
+	defer stop()
+	if s.Addr().String() != path {
+		t.Errorf("unexpected address; expected %s, got %s", path, s.Addr())
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	rsp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code; got %v, want %v", rsp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServer_StartListenErr(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := h.New(h.WithAddr(l.Addr().String()))
+	err = s.Start(context.Background())
+	if err == nil {
+		t.Error("expected start on a bound address to fail")
+	}
+	if s.State() != h.StateStopped {
+		t.Errorf("unexpected state; expected %v, got %v", h.StateStopped, s.State())
+	}
+}
+
+func TestWithListener_StartErr(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := h.New(h.WithListener(l), h.WithTLS("missing.crt", "missing.key"))
+	err = s.Start(context.Background())
+	if err == nil {
+		t.Fatal("expected start with missing certificate files to fail")
+	}
+	if s.State() != h.StateStopped {
+		t.Errorf("unexpected state; expected %v, got %v", h.StateStopped, s.State())
+	}
+	// the listener is closed when start fails
+	_, err = net.Dial("tcp", l.Addr().String())
+	if err == nil {
+		t.Error("expected listener to be closed")
+	}
+}
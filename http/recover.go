@@ -0,0 +1,124 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+)
+
+// A PanicHook is called with a panic recovered by Recoverer and the stack
+// trace of the panicking goroutine, e.g. to record the panic on a span
+type PanicHook func(r *http.Request, p interface{}, stack []byte)
+
+// Recoverer returns a middleware recovering panics in next. The panic is
+// logged with its stack trace and the request ID, added as the fieldKey
+// field, and an internal error response is written as RequestErr.
+//
+// If the handler had already written the response headers no response is
+// written, the connection is aborted instead so the client does not mistake
+// a partial response for a complete one.
+//
+// Example:
+//
+//	DefaultRequestLogger(log, "requestid", "Request-Id")(
+//		Recoverer(log, "requestid", "Request-Id", otel.RecordPanic)(handler),
+//	)
+func Recoverer(log zerolog.Logger, fieldKey, headerName string, hooks ...PanicHook) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &headerWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					// deliberate abort, not logged by net/http either
+					panic(p)
+				}
+				stack := debug.Stack()
+				for _, hook := range hooks {
+					hook(r, p, stack)
+				}
+				c := log.With().Str("stack", string(stack))
+				if id, ok := IDFromRequest(r, headerName); ok && id != "" && fieldKey != "" {
+					c = c.Str(fieldKey, id)
+				}
+				l := c.Logger()
+				err := fmt.Errorf("panic: %v", p)
+				if rw.wroteHeader {
+					l.Error().Err(err).Msg("panic after response headers were written")
+					panic(http.ErrAbortHandler)
+				}
+				RequestErr(http.StatusInternalServerError, l, w, err, http.StatusText(http.StatusInternalServerError))
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// headerWriter records whether the response headers have been written
+type headerWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *headerWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying writer does
+func (w *headerWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer does
+func (w *headerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker not implemented")
+	}
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+// Push implements http.Pusher, returning http.ErrNotSupported if the
+// underlying writer does not support server push
+func (w *headerWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
+// ReadFrom implements io.ReaderFrom so the underlying writer can still
+// send files efficiently, copying r otherwise
+func (w *headerWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.wroteHeader = true
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(writerOnly{w.ResponseWriter}, r)
+}
+
+// writerOnly hides the ReadFrom method of a writer so io.Copy does not
+// call it
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,186 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	h "go.soon.build/kit/http"
+)
+
+func TestRecoverer(t *testing.T) {
+	var hooked interface{}
+	hook := func(r *http.Request, p interface{}, stack []byte) {
+		hooked = p
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	logWriter := bytes.Buffer{}
+	log := zerolog.New(&logWriter)
+	chain := h.RequestIDHandler("", "Request-ID")(
+		h.Recoverer(log, "requestid", "Request-ID", hook)(handler),
+	)
+	w := httptest.NewRecorder()
+	chain.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected response status; expected %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	var body map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["errID"] == "" || body["errID"] == nil {
+		t.Error("missing errID")
+	}
+	if hooked != "boom" {
+		t.Errorf("unexpected hook panic; expected %s, got %v", "boom", hooked)
+	}
+	entries := logEntriesFromBuffer(logWriter)
+	if len(entries) != 1 {
+		t.Fatalf("unexpected log entries; expected %d entries, got %d", 1, len(entries))
+	}
+	entry := entries[0]
+	if entry["error"] != "panic: boom" {
+		t.Errorf("unexpected log field; expected %s, got %v", "panic: boom", entry["error"])
+	}
+	if entry["errID"] != body["errID"] {
+		t.Errorf("unexpected log field; expected %v, got %v", body["errID"], entry["errID"])
+	}
+	if entry["requestid"] != w.Header().Get("Request-ID") {
+		t.Errorf("unexpected log field; expected %s, got %v", w.Header().Get("Request-ID"), entry["requestid"])
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "recover_test.go") {
+		t.Errorf("unexpected stack trace; got %s", stack)
+	}
+}
+
+func TestRecoverer_HeadersWritten(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "partial")
+		panic("boom")
+	})
+	logWriter := bytes.Buffer{}
+	chain := h.Recoverer(zerolog.New(&logWriter), "", "")(handler)
+	w := httptest.NewRecorder()
+	func() {
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("unexpected panic; expected %v, got %v", http.ErrAbortHandler, p)
+			}
+		}()
+		chain.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+	}()
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected response status; expected %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "partial" {
+		t.Errorf("unexpected body; expected %s, got %s", "partial", w.Body.String())
+	}
+	entries := logEntriesFromBuffer(logWriter)
+	if len(entries) != 1 || entries[0]["error"] != "panic: boom" {
+		t.Errorf("unexpected log entries; got %v", entries)
+	}
+}
+
+func TestRecoverer_Flusher(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("expected response writer to implement http.Flusher")
+		}
+	})
+	chain := h.Recoverer(zerolog.Nop(), "", "")(handler)
+	chain.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/foo", nil))
+}
+
+// pushRecorder is a recorder implementing http.Pusher and io.ReaderFrom
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed   []string
+	readFrom bool
+}
+
+func (w *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+func (w *pushRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func TestRecoverer_Pusher(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := w.(http.Pusher)
+		if !ok {
+			t.Fatal("expected response writer to implement http.Pusher")
+		}
+		if err := p.Push("/app.js", nil); err != nil {
+			t.Error(err)
+		}
+	})
+	chain := h.Recoverer(zerolog.Nop(), "", "")(handler)
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	chain.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+	if len(w.pushed) != 1 || w.pushed[0] != "/app.js" {
+		t.Errorf("unexpected pushed targets; expected %v, got %v", []string{"/app.js"}, w.pushed)
+	}
+
+	// push is not supported by the recorder
+	handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/app.js", nil); err != http.ErrNotSupported {
+			t.Errorf("unexpected error; expected %v, got %v", http.ErrNotSupported, err)
+		}
+	})
+	chain = h.Recoverer(zerolog.Nop(), "", "")(handler)
+	chain.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/foo", nil))
+}
+
+func TestRecoverer_ReaderFrom(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// hide WriteTo so io.Copy uses the writer ReadFrom
+		_, _ = io.Copy(w, struct{ io.Reader }{strings.NewReader("partial")})
+		panic("boom")
+	})
+	tc := map[string]struct {
+		w         http.ResponseWriter
+		xReadFrom bool
+	}{
+		"reader from": {w: &pushRecorder{ResponseRecorder: httptest.NewRecorder()}, xReadFrom: true},
+		"copy":        {w: httptest.NewRecorder()},
+	}
+	for name, tt := range tc {
+		t.Run(name, func(t *testing.T) {
+			chain := h.Recoverer(zerolog.Nop(), "", "")(handler)
+			func() {
+				defer func() {
+					if p := recover(); p != http.ErrAbortHandler {
+						t.Errorf("unexpected panic; expected %v, got %v", http.ErrAbortHandler, p)
+					}
+				}()
+				chain.ServeHTTP(tt.w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+			}()
+			var body string
+			switch w := tt.w.(type) {
+			case *pushRecorder:
+				body = w.Body.String()
+				if !w.readFrom {
+					t.Error("expected ReadFrom of the response writer to be used")
+				}
+			case *httptest.ResponseRecorder:
+				body = w.Body.String()
+			}
+			if body != "partial" {
+				t.Errorf("unexpected body; expected %s, got %s", "partial", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultReloadInterval is how often certificate files are checked for
+// changes
+const defaultReloadInterval = time.Second * 10
+
+// A TLSOption configures the TLS settings of a server
+type TLSOption func(*certReloader)
+
+// WithMinVersion returns a TLSOption to configure the minimum TLS version
+// accepted, the default is TLS 1.2
+func WithMinVersion(v uint16) TLSOption {
+	return func(r *certReloader) {
+		r.minVersion = v
+	}
+}
+
+// WithClientAuth returns a TLSOption to configure the client certificate
+// policy of WithMTLS, the default is tls.RequireAndVerifyClientCert
+func WithClientAuth(auth tls.ClientAuthType) TLSOption {
+	return func(r *certReloader) {
+		r.clientAuth = auth
+	}
+}
+
+// WithReloadInterval returns a TLSOption to configure how often the
+// certificate files are checked for changes, the default is 10 seconds
+func WithReloadInterval(d time.Duration) TLSOption {
+	return func(r *certReloader) {
+		r.interval = d
+	}
+}
+
+// WithTLS returns an Option to serve HTTPS with the PEM encoded certificate
+// and key files. The files are reloaded when they change on disk, e.g. when
+// a certificate is rotated, new connections use the new certificate without
+// dropping existing connections. Other settings of a TLS config set on the
+// Srv field by an Option, e.g. cipher suites, are kept.
+//
+// Example:
+//
+//	srv := http.New(http.WithTLS("/etc/tls/tls.crt", "/etc/tls/tls.key"))
+func WithTLS(certFile, keyFile string, opts ...TLSOption) Option {
+	return withTLS(certFile, keyFile, "", opts...)
+}
+
+// WithMTLS returns an Option to serve HTTPS as WithTLS, additionally
+// requiring clients to present a certificate signed by a CA in the PEM
+// encoded caFile. The CA file is reloaded along with the certificate. The
+// identity of a verified client is available to handlers with
+// ClientIdentityFromCtx.
+//
+// Example:
+//
+//	srv := http.New(http.WithMTLS("/etc/tls/tls.crt", "/etc/tls/tls.key", "/etc/tls/ca.crt"))
+func WithMTLS(certFile, keyFile, caFile string, opts ...TLSOption) Option {
+	return withTLS(certFile, keyFile, caFile, opts...)
+}
+
+func withTLS(certFile, keyFile, caFile string, opts ...TLSOption) Option {
+	return func(s *Server) {
+		r := &certReloader{
+			certFile:   certFile,
+			keyFile:    keyFile,
+			caFile:     caFile,
+			minVersion: tls.VersionTLS12,
+			clientAuth: tls.RequireAndVerifyClientCert,
+			interval:   defaultReloadInterval,
+			modTimes:   map[string]time.Time{},
+			log:        &s.log,
+		}
+		for _, opt := range opts {
+			opt(r)
+		}
+		s.tls = r
+	}
+}
+
+// A certReloader holds a certificate, and optionally a client CA pool,
+// loaded from files which are reloaded when they change
+type certReloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+	interval   time.Duration
+	log        *zerolog.Logger
+
+	mu       sync.Mutex // protects the fields below
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes map[string]time.Time
+	checked  time.Time
+}
+
+// load reads the certificate files
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.read()
+}
+
+// read reads the certificate files, r.mu must be held
+func (r *certReloader) read() error {
+	modTimes := map[string]time.Time{}
+	for _, p := range r.files() {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		modTimes[p] = fi.ModTime()
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %v", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		b, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("error loading client CA: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.New("error loading client CA: no certificates found")
+		}
+	}
+	r.cert, r.pool, r.modTimes = &cert, pool, modTimes
+	r.checked = time.Now()
+	return nil
+}
+
+func (r *certReloader) files() []string {
+	if r.caFile == "" {
+		return []string{r.certFile, r.keyFile}
+	}
+	return []string{r.certFile, r.keyFile, r.caFile}
+}
+
+// current returns the current certificate and CA pool, reloading them if
+// the files have changed since they were last checked. On reload error the
+// previous certificate is kept.
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert == nil {
+		err := r.read()
+		return r.cert, r.pool, err
+	}
+	if time.Since(r.checked) < r.interval {
+		return r.cert, r.pool, nil
+	}
+	r.checked = time.Now()
+	changed := false
+	for _, p := range r.files() {
+		fi, err := os.Stat(p)
+		if err != nil {
+			r.log.Error().Err(err).Msg("error checking certificate files")
+			return r.cert, r.pool, nil
+		}
+		if !fi.ModTime().Equal(r.modTimes[p]) {
+			changed = true
+		}
+	}
+	if changed {
+		err := r.read()
+		if err != nil {
+			// a rotation may be partially written, retried on the next check
+			r.log.Error().Err(err).Msg("error reloading certificate files")
+			return r.cert, r.pool, nil
+		}
+		r.log.Info().Msg("reloaded certificate files")
+	}
+	return r.cert, r.pool, nil
+}
+
+// config returns the server TLS config, based on a clone of base if it is
+// not nil so settings such as cipher suites are kept
+func (r *certReloader) config(base *tls.Config) *tls.Config {
+	c := r.clone(base)
+	if r.caFile != "" {
+		// the CA pool may change so the config is built per connection
+		c.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			_, pool, err := r.current()
+			if err != nil {
+				return nil, err
+			}
+			cc := r.clone(base)
+			cc.GetConfigForClient = nil
+			cc.ClientAuth = r.clientAuth
+			cc.ClientCAs = pool
+			if len(cc.NextProtos) == 0 {
+				// set on the server config by ServeTLS, but not on
+				// configs returned per connection
+				cc.NextProtos = []string{"h2", "http/1.1"}
+			}
+			return cc, nil
+		}
+	}
+	return c
+}
+
+// clone returns a clone of base serving the reloaded certificate
+func (r *certReloader) clone(base *tls.Config) *tls.Config {
+	c := &tls.Config{}
+	if base != nil {
+		c = base.Clone()
+	}
+	if c.MinVersion < r.minVersion {
+		c.MinVersion = r.minVersion
+	}
+	c.Certificates = nil
+	c.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _, err := r.current()
+		return cert, err
+	}
+	return c
+}
+
+// ClientIdentity describes the verified certificate presented by a client
+// of a server configured WithMTLS
+type ClientIdentity struct {
+	CommonName  string
+	DNSNames    []string
+	URIs        []string // e.g. SPIFFE IDs
+	Certificate *x509.Certificate
+}
+
+type clientIdentityKey struct{}
+
+// ClientIdentityFromCtx returns the identity of the verified client
+// certificate of a request, if any
+func ClientIdentityFromCtx(ctx context.Context) (ClientIdentity, bool) {
+	id, ok := ctx.Value(clientIdentityKey{}).(ClientIdentity)
+	return id, ok
+}
+
+// clientIdentityHandler adds the identity of a verified client certificate
+// to the request context
+func clientIdentityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			cert := r.TLS.VerifiedChains[0][0]
+			id := ClientIdentity{
+				CommonName:  cert.Subject.CommonName,
+				DNSNames:    cert.DNSNames,
+				Certificate: cert,
+			}
+			for _, u := range cert.URIs {
+				id.URIs = append(id.URIs, u.String())
+			}
+			r = r.WithContext(context.WithValue(r.Context(), clientIdentityKey{}, id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,265 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	h "go.soon.build/kit/http"
+)
+
+// testCA signs certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue writes a certificate and key signed by the CA to dir, returning the
+// file paths
+func (ca *testCA) issue(t *testing.T, dir, cn string, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, cn+".crt"), filepath.Join(dir, cn+".key")
+	writeTestFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeTestFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}))
+	return certFile, keyFile
+}
+
+func writeTestFile(t *testing.T, p string, b []byte) {
+	err := ioutil.WriteFile(p, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startTLS serves the server handler with its TLS config
+func startTLS(s *h.Server) *httptest.Server {
+	ts := httptest.NewUnstartedServer(s.Srv.Handler)
+	ts.TLS = s.Srv.TLSConfig
+	ts.StartTLS()
+	return ts
+}
+
+func tlsClient(ca *testCA, cfg *tls.Config) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	cfg.RootCAs = pool
+	cfg.ServerName = "localhost"
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   cfg,
+		DisableKeepAlives: true,
+	}}
+}
+
+func TestWithTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	s := h.New(h.WithTLS(certFile, keyFile, h.WithReloadInterval(0)))
+	ts := startTLS(s)
+	defer ts.Close()
+	client := tlsClient(ca, &tls.Config{})
+
+	rsp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if cn := rsp.TLS.PeerCertificates[0].Subject.CommonName; cn != "server" {
+		t.Errorf("unexpected certificate; expected %s, got %s", "server", cn)
+	}
+
+	// rotate the certificate
+	rotated, rotatedKey := ca.issue(t, dir, "rotated", x509.ExtKeyUsageServerAuth)
+	for src, dst := range map[string]string{rotated: certFile, rotatedKey: keyFile} {
+		err = os.Rename(src, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		future := time.Now().Add(time.Minute)
+		err = os.Chtimes(dst, future, future)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rsp, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if cn := rsp.TLS.PeerCertificates[0].Subject.CommonName; cn != "rotated" {
+		t.Errorf("unexpected certificate; expected %s, got %s", "rotated", cn)
+	}
+
+	// minimum version
+	_, err = tlsClient(ca, &tls.Config{MaxVersion: tls.VersionTLS11}).Get(ts.URL)
+	if err == nil {
+		t.Error("expected TLS 1.1 handshake to fail")
+	}
+}
+
+func TestWithMTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeTestFile(t, caFile, ca.pem)
+	clientCert, clientKey := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+
+	identities := make(chan h.ClientIdentity, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := h.ClientIdentityFromCtx(r.Context())
+		identities <- id
+	})
+	s := h.New(h.WithHandler(handler), h.WithMTLS(certFile, keyFile, caFile))
+	ts := startTLS(s)
+	defer ts.Close()
+
+	_, err = tlsClient(ca, &tls.Config{}).Get(ts.URL)
+	if err == nil {
+		t.Error("expected request without client certificate to fail")
+	}
+
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := tlsClient(ca, &tls.Config{Certificates: []tls.Certificate{cert}}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	id := <-identities
+	if id.CommonName != "client" {
+		t.Errorf("unexpected client identity; expected %s, got %s", "client", id.CommonName)
+	}
+}
+
+func TestWithTLS_StartErr(t *testing.T) {
+	s := h.New(h.WithAddr(":5443"), h.WithTLS("missing.crt", "missing.key"))
+	err := s.Start(context.Background())
+	if err == nil {
+		t.Error("expected start with missing certificate files to fail")
+	}
+}
+
+func TestWithTLS_BaseConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeTestFile(t, caFile, ca.pem)
+	clientCert, clientKey := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := map[string]h.Option{
+		"tls":  h.WithTLS(certFile, keyFile),
+		"mtls": h.WithMTLS(certFile, keyFile, caFile),
+	}
+	for name, opt := range tc {
+		t.Run(name, func(t *testing.T) {
+			base := &tls.Config{MinVersion: tls.VersionTLS13}
+			s := h.New(opt, func(s *h.Server) {
+				s.Srv.TLSConfig = base
+			})
+			if base.GetCertificate != nil {
+				t.Error("expected base config not to be modified")
+			}
+			ts := startTLS(s)
+			defer ts.Close()
+
+			_, err := tlsClient(ca, &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MaxVersion:   tls.VersionTLS12,
+			}).Get(ts.URL)
+			if err == nil {
+				t.Error("expected TLS 1.2 handshake to fail")
+			}
+			rsp, err := tlsClient(ca, &tls.Config{Certificates: []tls.Certificate{cert}}).Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp.Body.Close()
+			if rsp.TLS.Version != tls.VersionTLS13 {
+				t.Errorf("unexpected TLS version; expected %x, got %x", tls.VersionTLS13, rsp.TLS.Version)
+			}
+		})
+	}
+}
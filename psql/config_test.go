@@ -0,0 +1,41 @@
+package psql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigPool(t *testing.T) {
+	cases := map[string]struct {
+		config Config
+		want   pool
+	}{
+		"defaults": {
+			config: Config{},
+			want: pool{
+				maxConns:     20,
+				maxIdleConns: 0,
+				maxLifetime:  time.Second * 10,
+			},
+		},
+		"configured": {
+			config: Config{
+				MaxConnections:     50,
+				MaxIdleConnections: 5,
+				ConnMaxLifetime:    time.Minute,
+			},
+			want: pool{
+				maxConns:     50,
+				maxIdleConns: 5,
+				maxLifetime:  time.Minute,
+			},
+		},
+	}
+	for name, testCase := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.want, testCase.config.pool())
+		})
+	}
+}
@@ -8,14 +8,17 @@ import (
 	"time"
 )
 
-// Config contains data source name settings
+// Config contains data source name and connection pool settings, it can be
+// loaded with the kit config package as part of a service configuration
 type Config struct {
-	User           string
-	Pass           string
-	DBName         string
-	Host           string
-	SSLMode        string
-	MaxConnections int
+	User               string        `mapstructure:"user" desc:"database user"`
+	Pass               string        `mapstructure:"pass" secret:"true" desc:"database password"`
+	DBName             string        `mapstructure:"dbName" desc:"database name"`
+	Host               string        `mapstructure:"host" default:"localhost:5432" desc:"database host and port"`
+	SSLMode            string        `mapstructure:"sslMode" default:"disable" desc:"postgres sslmode"`
+	MaxConnections     int           `mapstructure:"maxConnections" default:"20" desc:"maximum open connections"`
+	MaxIdleConnections int           `mapstructure:"maxIdleConnections" desc:"maximum idle connections"`
+	ConnMaxLifetime    time.Duration `mapstructure:"connMaxLifetime" default:"10s" desc:"maximum time a connection may be reused"`
 }
 
 // DSN returns the data source name as a string in the
@@ -40,13 +43,34 @@ func Open(ctx context.Context, config Config) (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	p := config.pool()
+	db.SetMaxOpenConns(p.maxConns)
+	db.SetMaxIdleConns(p.maxIdleConns)
+	db.SetConnMaxLifetime(p.maxLifetime)
+	return db, nil
+}
+
+// pool holds the connection pool settings applied by Open
+type pool struct {
+	maxConns     int
+	maxIdleConns int
+	maxLifetime  time.Duration
+}
+
+// pool returns the connection pool settings from config, falling back to
+// defaults for zero values
+func (config Config) pool() pool {
 	// https://aaronoellis.com/articles/preventing-max-connection-errors-in-go
-	var maxConns = 20 // Sane default
+	p := pool{
+		maxConns:     20, // Sane default
+		maxIdleConns: config.MaxIdleConnections,
+		maxLifetime:  time.Second * 10,
+	}
 	if config.MaxConnections != 0 {
-		maxConns = config.MaxConnections
+		p.maxConns = config.MaxConnections
 	}
-	db.SetMaxOpenConns(maxConns)
-	db.SetMaxIdleConns(0)
-	db.SetConnMaxLifetime(time.Second * 10)
-	return db, nil
+	if config.ConnMaxLifetime != 0 {
+		p.maxLifetime = config.ConnMaxLifetime
+	}
+	return p
 }
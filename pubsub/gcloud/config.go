@@ -0,0 +1,61 @@
+package gcloud
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Config holds topic and subscription settings which can be loaded with the
+// kit config package as part of a service configuration
+//
+// Example:
+//
+//	type Config struct {
+//		PubSub gcloud.Config `mapstructure:"pubsub"`
+//	}
+//	...
+//	ps, err := gcloud.NewFromConfig(ctx, client, c.PubSub)
+type Config struct {
+	Topic        string             `mapstructure:"topic" validate:"required" desc:"topic to publish and subscribe to"`
+	Subscription SubscriptionConfig `mapstructure:"subscription"`
+}
+
+// SubscriptionConfig holds subscription settings, zero values keep the
+// pubsub client defaults
+type SubscriptionConfig struct {
+	Name                   string        `mapstructure:"name" default:"kit" desc:"subscription name"`
+	MaxOutstandingMessages int           `mapstructure:"maxOutstandingMessages" desc:"maximum unprocessed messages"`
+	MaxExtension           time.Duration `mapstructure:"maxExtension" desc:"maximum time to extend a message ack deadline"`
+	NumGoroutines          int           `mapstructure:"numGoroutines" desc:"number of goroutines pulling messages"`
+}
+
+// Options returns the Options configuring a Gcloud instance from c
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Subscription.Name != "" {
+		opts = append(opts, WithSubName(c.Subscription.Name))
+	}
+	s := c.Subscription
+	if s.MaxOutstandingMessages != 0 || s.MaxExtension != 0 || s.NumGoroutines != 0 {
+		rs := pubsub.DefaultReceiveSettings
+		if s.MaxOutstandingMessages != 0 {
+			rs.MaxOutstandingMessages = s.MaxOutstandingMessages
+		}
+		if s.MaxExtension != 0 {
+			rs.MaxExtension = s.MaxExtension
+		}
+		if s.NumGoroutines != 0 {
+			rs.NumGoroutines = s.NumGoroutines
+		}
+		opts = append(opts, WithReceiveSettings(rs))
+	}
+	return opts
+}
+
+// NewFromConfig sets up a Gcloud instance from c, opts are applied after
+// the configured settings so they take precedence. See New.
+func NewFromConfig(ctx context.Context, client *pubsub.Client, c Config, opts ...Option) (*Gcloud, error) {
+	return New(ctx, c.Topic, client, append(c.Options(), opts...)...)
+}
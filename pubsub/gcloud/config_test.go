@@ -0,0 +1,67 @@
+package gcloud
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// apply applies opts to a Gcloud instance with the New defaults
+func apply(opts []Option) *Gcloud {
+	p := &Gcloud{subName: "kit"}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func TestConfig_Options(t *testing.T) {
+	p := apply(Config{
+		Topic: "test",
+		Subscription: SubscriptionConfig{
+			Name:                   "sub",
+			MaxOutstandingMessages: 10,
+			MaxExtension:           time.Minute,
+		},
+	}.Options())
+	if p.subName != "sub" {
+		t.Errorf("unexpected subscription name; expected %s, got %s", "sub", p.subName)
+	}
+	if p.receive == nil {
+		t.Fatal("expected receive settings to be set")
+	}
+	if p.receive.MaxOutstandingMessages != 10 {
+		t.Errorf("unexpected max outstanding messages; expected %d, got %d", 10, p.receive.MaxOutstandingMessages)
+	}
+	if p.receive.MaxExtension != time.Minute {
+		t.Errorf("unexpected max extension; expected %v, got %v", time.Minute, p.receive.MaxExtension)
+	}
+	// unset settings keep the pubsub defaults
+	if p.receive.NumGoroutines != pubsub.DefaultReceiveSettings.NumGoroutines {
+		t.Errorf("unexpected num goroutines; expected %d, got %d", pubsub.DefaultReceiveSettings.NumGoroutines, p.receive.NumGoroutines)
+	}
+}
+
+func TestConfig_Options_Defaults(t *testing.T) {
+	opts := Config{Topic: "test"}.Options()
+	if len(opts) != 0 {
+		t.Errorf("unexpected options for zero config; got %d, want 0", len(opts))
+	}
+	p := apply(opts)
+	if p.subName != "kit" {
+		t.Errorf("unexpected subscription name; expected %s, got %s", "kit", p.subName)
+	}
+	if p.receive != nil {
+		t.Errorf("unexpected receive settings; got %+v", *p.receive)
+	}
+}
+
+func TestWithReceiveSettings(t *testing.T) {
+	rs := pubsub.ReceiveSettings{NumGoroutines: 2}
+	p := apply([]Option{WithReceiveSettings(rs)})
+	rs.NumGoroutines = 4
+	if p.receive == nil || p.receive.NumGoroutines != 2 {
+		t.Errorf("unexpected receive settings; got %+v", p.receive)
+	}
+}
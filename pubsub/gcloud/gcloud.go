@@ -40,6 +40,7 @@ func (m *Message) EnrichContext(ctx context.Context) context.Context {
 // Gcloud is an implementation of Publisher/Subscriber for Google Cloud Pubsub
 type Gcloud struct {
 	subName    string
+	receive    *pubsub.ReceiveSettings
 	topic      *pubsub.Topic
 	client     *pubsub.Client
 	log        zerolog.Logger
@@ -66,6 +67,14 @@ func WithLogger(log zerolog.Logger) Option {
 	}
 }
 
+// WithReceiveSettings returns an Option to configure how messages are
+// received from the subscription
+func WithReceiveSettings(rs pubsub.ReceiveSettings) Option {
+	return func(p *Gcloud) {
+		p.receive = &rs
+	}
+}
+
 func WithPropagator(propagator propagation.TextMapPropagator) Option {
 	return func(p *Gcloud) {
 		p.propagator = propagator
@@ -147,6 +156,9 @@ func (p *Gcloud) Close() {
 func (p *Gcloud) Subscribe(ctx context.Context) (<-chan Message, error) {
 	c := make(chan Message)
 	sub := p.client.Subscription(p.subName)
+	if p.receive != nil {
+		sub.ReceiveSettings = *p.receive
+	}
 	log := p.log.With().Str("subscription", sub.ID()).Logger()
 	go func() {
 		log.Debug().Msg("receiving from subscription")
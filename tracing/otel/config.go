@@ -0,0 +1,85 @@
+package otel
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Holds provider settings which can be loaded with the kit config package as part of a
+// service configuration.
+//
+// Example:
+//
+//	type Config struct {
+//		Tracing otel.Config `mapstructure:"tracing"`
+//	}
+//	...
+//	provider, err := otel.NewOtelProviderFromConfig(c.Tracing)
+type Config struct {
+	ServiceName      string         `mapstructure:"serviceName" validate:"required" desc:"service name attached to spans"`
+	ServiceNamespace string         `mapstructure:"serviceNamespace" desc:"service namespace, usually the overarching project"`
+	ServiceVersion   string         `mapstructure:"serviceVersion" desc:"service version attached to spans"`
+	TracerName       string         `mapstructure:"tracerName" desc:"tracer name, defaults to the service name"`
+	GCPTraceLogger   bool           `mapstructure:"gcpTraceLogger" desc:"add GCP trace fields to span loggers"`
+	Exporter         ExporterConfig `mapstructure:"exporter"`
+	Sampler          SamplerConfig  `mapstructure:"sampler"`
+}
+
+// Holds span exporter settings.
+type ExporterConfig struct {
+	Type      string `mapstructure:"type" default:"none" validate:"oneof=none gcp" desc:"span exporter, none or gcp"`
+	ProjectID string `mapstructure:"projectId" desc:"GCP project ID for the gcp exporter"`
+}
+
+// Holds trace sampler settings. When the type is not set the sampler is configured from the
+// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG env vars.
+type SamplerConfig struct {
+	Type  string  `mapstructure:"type" validate:"oneof=always_on always_off traceidratio parentbased_always_on parentbased_always_off parentbased_traceidratio" desc:"trace sampler, as for OTEL_TRACES_SAMPLER"`
+	Ratio float64 `mapstructure:"ratio" default:"1" validate:"max=1" desc:"sampling ratio for the traceidratio samplers"`
+}
+
+// Returns the options configuring a provider from the config.
+//
+// If the exporter or sampler settings are invalid, the returned options will return an error
+// when applied.
+func (c Config) Options() []OtelProviderOption {
+	var opts []OtelProviderOption
+	if c.ServiceNamespace != "" {
+		opts = append(opts, WithServiceNamespace(c.ServiceNamespace))
+	}
+	if c.ServiceVersion != "" {
+		opts = append(opts, WithServiceVersion(c.ServiceVersion))
+	}
+	if c.TracerName != "" {
+		opts = append(opts, WithTracerName(c.TracerName))
+	}
+	if c.GCPTraceLogger {
+		opts = append(opts, WithGCPTraceLogger())
+	}
+	switch c.Exporter.Type {
+	case "", "none":
+	case "gcp":
+		opts = append(opts, WithGcpExporter(c.Exporter.ProjectID))
+	default:
+		opts = append(opts, func(op *OtelProvider) error {
+			return fmt.Errorf("unknown exporter: %s", c.Exporter.Type)
+		})
+	}
+	if c.Sampler.Type != "" {
+		opts = append(opts, func(op *OtelProvider) error {
+			sampler, err := newSampler(c.Sampler.Type, strconv.FormatFloat(c.Sampler.Ratio, 'f', -1, 64))
+			if err != nil {
+				return err
+			}
+			op.sampler = sampler
+			return nil
+		})
+	}
+	return opts
+}
+
+// Constructs a new OtelProvider from the given config, any options are applied after the
+// configured settings so they take priority.
+func NewOtelProviderFromConfig(c Config, opts ...OtelProviderOption) (*OtelProvider, error) {
+	return NewOtelProvider(c.ServiceName, append(c.Options(), opts...)...)
+}
@@ -0,0 +1,66 @@
+package otel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOtelProviderFromConfig(t *testing.T) {
+	cases := map[string]struct {
+		config     Config
+		customTest func(*testing.T, *OtelProvider, error)
+	}{
+		"service": {
+			config: Config{
+				ServiceName:      "test-service",
+				ServiceNamespace: "test-namespace",
+				ServiceVersion:   "v1.0.0",
+			},
+			customTest: func(t *testing.T, op *OtelProvider, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "test-service", op.serviceName)
+				assert.Equal(t, "test-namespace", op.serviceNamespace)
+				assert.Equal(t, "v1.0.0", op.serviceVersion)
+				assert.Nil(t, op.exporter)
+				assert.Nil(t, op.sampler)
+			},
+		},
+		"sampler": {
+			config: Config{
+				ServiceName: "test-service",
+				Sampler:     SamplerConfig{Type: "parentbased_traceidratio", Ratio: 0.5},
+			},
+			customTest: func(t *testing.T, op *OtelProvider, err error) {
+				assert.NoError(t, err)
+				assert.Contains(t, op.sampler.Description(), "TraceIDRatioBased{0.5}")
+			},
+		},
+		"unknown sampler": {
+			config: Config{
+				ServiceName: "test-service",
+				Sampler:     SamplerConfig{Type: "foo"},
+			},
+			customTest: func(t *testing.T, op *OtelProvider, err error) {
+				assert.Error(t, err)
+				assert.Nil(t, op)
+			},
+		},
+		"unknown exporter": {
+			config: Config{
+				ServiceName: "test-service",
+				Exporter:    ExporterConfig{Type: "foo"},
+			},
+			customTest: func(t *testing.T, op *OtelProvider, err error) {
+				assert.EqualError(t, err, "unknown exporter: foo")
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		t.Run(name, func(t *testing.T) {
+			provider, err := NewOtelProviderFromConfig(testCase.config)
+			testCase.customTest(t, provider, err)
+		})
+	}
+}
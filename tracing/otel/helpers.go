@@ -2,6 +2,8 @@ package otel
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
@@ -27,6 +29,22 @@ func SpanRecordError(span trace.Span, err error, description string, eventOption
 	span.RecordError(err, eventOptions...)
 }
 
+// RecordPanic records a panic recovered while handling a request on the
+// active span of the request, it can be used as a kit http.PanicHook
+//
+// Example:
+//
+//	http.Recoverer(log, "requestid", "Request-Id", otel.RecordPanic)(handler)
+func RecordPanic(r *http.Request, p interface{}, stack []byte) {
+	span := trace.SpanFromContext(r.Context())
+	if !span.IsRecording() {
+		return
+	}
+	SpanRecordError(span, fmt.Errorf("panic: %v", p), "panic",
+		trace.WithAttributes(attribute.String("exception.stacktrace", string(stack))),
+	)
+}
+
 var defaultGCPTraceLog = gcpTraceLog{
 	spanFieldName:  "logging.googleapis.com/spanId",
 	traceFieldName: "logging.googleapis.com/trace",
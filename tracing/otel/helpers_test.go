@@ -3,10 +3,13 @@ package otel
 import (
 	"bytes"
 	"context"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
 )
@@ -116,3 +119,38 @@ func (m *mockSpan) SpanContext() trace.SpanContext {
 func (m *mockSpan) TracerProvider() trace.TracerProvider {
 	return noop.NewTracerProvider()
 }
+
+// recordingSpan records the errors recorded on it
+type recordingSpan struct {
+	mockSpan
+	status codes.Code
+	errs   []error
+	attrs  []attribute.KeyValue
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {}
+
+func (s *recordingSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+	cfg := trace.NewEventConfig(opts...)
+	s.attrs = append(s.attrs, cfg.Attributes()...)
+}
+
+func TestRecordPanic(t *testing.T) {
+	span := &recordingSpan{}
+	r := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	r = r.WithContext(trace.ContextWithSpan(r.Context(), span))
+	RecordPanic(r, "boom", []byte("stack"))
+	assert.Equal(t, codes.Error, span.status)
+	if assert.Len(t, span.errs, 1) {
+		assert.EqualError(t, span.errs[0], "panic: boom")
+	}
+	assert.Contains(t, span.attrs, attribute.String("exception.stacktrace", "stack"))
+
+	// no active span
+	RecordPanic(httptest.NewRequest("GET", "http://example.com/foo", nil), "boom", nil)
+}
@@ -36,6 +36,7 @@ type OtelProvider struct {
 	tracerProviderOptions []sdktrace.TracerProviderOption
 
 	exporter sdktrace.SpanExporter
+	sampler  sdktrace.Sampler
 
 	getTraceLogger getTraceLogger
 }
@@ -169,6 +170,15 @@ func WithGcpExporter(projectId string) OtelProviderOption {
 	return WithGcpExporterAndOptions(nil, gcpexporter.WithProjectID(projectId))
 }
 
+// Sets the sampler used by the trace provider. If not specified the sampler is configured
+// from the OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG env vars.
+func WithSampler(sampler sdktrace.Sampler) OtelProviderOption {
+	return func(op *OtelProvider) error {
+		op.sampler = sampler
+		return nil
+	}
+}
+
 // Sets up the global OTEL SDK state to use the specified configuration, with sane-ish defaults.
 //
 // A new Resource is created using certain defaults as well as anything passed in from `WithResourceOptions`.
@@ -194,9 +204,12 @@ func (o *OtelProvider) SetupGlobalState(ctx context.Context) (CleanupFunc, error
 		return func() {}, err
 	}
 
-	sampler, err := samplerFromEnv()
-	if err != nil {
-		return func() {}, err
+	sampler := o.sampler
+	if sampler == nil {
+		sampler, err = samplerFromEnv()
+		if err != nil {
+			return func() {}, err
+		}
 	}
 
 	opts := append(
@@ -290,8 +303,17 @@ type getTraceLogger interface {
 // the obscure piece of documentation on why this happens, we've instead
 // decided to handle some of the env vars ourself.
 func samplerFromEnv() (sdktrace.Sampler, error) {
-	sampler := os.Getenv(otelSamplerEnvVar)
-	samplerArg := os.Getenv(otelSamplerArgEnvVar)
+	sampler, err := newSampler(os.Getenv(otelSamplerEnvVar), os.Getenv(otelSamplerArgEnvVar))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", otelSamplerEnvVar, err)
+	}
+	return sampler, nil
+}
+
+// Creates a sampler by its OTEL_TRACES_SAMPLER name, with the ratio used by the traceidratio
+// samplers given as a string in the OTEL_TRACES_SAMPLER_ARG format. Both default as in the
+// OTEL SDK, to sampling everything.
+func newSampler(sampler, samplerArg string) (sdktrace.Sampler, error) {
 	samplerArgFloat := 1.0
 
 	if samplerArg != "" {
@@ -318,6 +340,6 @@ func samplerFromEnv() (sdktrace.Sampler, error) {
 	case "":
 		return sdktrace.AlwaysSample(), nil
 	default:
-		return nil, fmt.Errorf("unknown value for %s: %s", otelSamplerEnvVar, sampler)
+		return nil, fmt.Errorf("unknown sampler: %s", sampler)
 	}
 }